@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+const usage = `downscalerctl inspects and operates the downscaler
+
+usage:
+  downscalerctl validate [-f downscaler.yaml] [--offline]
+  downscalerctl plan [-f downscaler.yaml] [--from 2024-06-10T00:00:00Z] [--to 2024-06-17T00:00:00Z]
+  downscalerctl status [--state_backend configmap]
+  downscalerctl wake <namespace> --for 2h
+  downscalerctl sleep <namespace> --for 2h
+
+every command reads the live Downscaler through the kubeconfig (--kubeconfig, --context, --name)
+unless a Downscaler yaml is provided with -f
+`
+
+type command func(ctx context.Context, args []string) error
+
+func main() {
+	commands := map[string]command{
+		"validate": runValidate,
+		"plan":     runPlan,
+		"status":   runStatus,
+		"wake":     runWake,
+		"sleep":    runSleep,
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	run, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/kubeclient"
+	"github.com/adalbertjnr/downscaler/shared"
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type options struct {
+	file       string
+	kubeconfig string
+	context    string
+	name       string
+}
+
+type clients struct {
+	client        *kubernetes.Clientset
+	dynamicClient *dynamic.DynamicClient
+	kubernetes    *kas.KubernetesImpl
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	opts := &options{}
+	fs.StringVar(&opts.file, "f", "", "read the Downscaler from a yaml file instead of the cluster")
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig (defaults to $KUBECONFIG or ~/.kube/config)")
+	fs.StringVar(&opts.context, "context", "", "kubeconfig context to use")
+	fs.StringVar(&opts.name, "name", shared.DownscalerNamespace, "name of the Downscaler object")
+	return fs, opts
+}
+
+func (o *options) clients() (*clients, error) {
+	config, err := kubeclient.NewConfig(kubeclient.Options{
+		Kubeconfig: o.kubeconfig,
+		Context:    o.context,
+		UserAgent:  shared.CtlUserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubeclient.NewClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := kubeclient.NewDynamicClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clients{
+		client:        client,
+		dynamicClient: dynamicClient,
+		kubernetes:    kas.NewKubernetes(client, dynamicClient),
+	}, nil
+}
+
+func (o *options) policy(ctx context.Context) (*shared.DownscalerPolicy, error) {
+	policy := &shared.DownscalerPolicy{}
+
+	if o.file != "" {
+		data, err := os.ReadFile(o.file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, policy); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", o.file, err)
+		}
+		return policy, nil
+	}
+
+	c, err := o.clients()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.kubernetes.GetDownscalerData(ctx, kas.DownscalerResource, o.name)
+}
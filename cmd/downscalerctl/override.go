@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+func runWake(ctx context.Context, args []string) error {
+	return runOverride(ctx, shared.OverrideWake, args)
+}
+
+func runSleep(ctx context.Context, args []string) error {
+	return runOverride(ctx, shared.OverrideSleep, args)
+}
+
+func runOverride(ctx context.Context, mode string, args []string) error {
+	fs, opts := newFlagSet(mode)
+	duration := fs.Duration("for", time.Hour, "how long the override lasts before the namespace returns to its schedule")
+	cancel := fs.Bool("cancel", false, "remove the current override of the namespace")
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: downscalerctl %s <namespace> --for 2h", mode)
+	}
+	namespace := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	c, err := opts.clients()
+	if err != nil {
+		return err
+	}
+
+	if *cancel {
+		if err := c.kubernetes.PatchNamespaceOverride(ctx, namespace, &shared.Override{Mode: mode, Until: time.Now()}); err != nil {
+			return err
+		}
+		fmt.Printf("namespace %s override cancelled, it returns to its schedule on the next downscaler cycle\n", namespace)
+		return nil
+	}
+
+	if *duration <= 0 {
+		return fmt.Errorf("--for must be greater than zero")
+	}
+
+	override := shared.Override{Mode: mode, Until: time.Now().Add(*duration)}
+	if err := c.kubernetes.PatchNamespaceOverride(ctx, namespace, &override); err != nil {
+		return err
+	}
+
+	fmt.Printf("namespace %s: %s\n", namespace, override.String())
+	return nil
+}
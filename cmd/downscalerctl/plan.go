@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/scheduler"
+	"github.com/adalbertjnr/downscaler/validation"
+)
+
+const defaultPlanWindow = time.Hour * 24 * 7
+
+func runPlan(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("plan")
+	fromFlag := fs.String("from", "", "start of the timeline in RFC3339 (defaults to now)")
+	toFlag := fs.String("to", "", "end of the timeline in RFC3339 (defaults to 7 days after --from)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	from := time.Now()
+	if *fromFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *fromFlag)
+		if err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+		from = parsed
+	}
+
+	to := from.Add(defaultPlanWindow)
+	if *toFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *toFlag)
+		if err != nil {
+			return fmt.Errorf("--to: %w", err)
+		}
+		to = parsed
+	}
+	if !to.After(from) {
+		return fmt.Errorf("--to must be after --from")
+	}
+
+	policy, err := opts.policy(ctx)
+	if err != nil {
+		return err
+	}
+
+	if errs := validation.Policy(policy); len(errs) > 0 {
+		return fmt.Errorf("the downscaler policy is not valid, run downscalerctl validate")
+	}
+
+	transitions, err := scheduler.Plan(policy, from, to)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tTIME\tTRANSITION\tRULE")
+	for _, transition := range transitions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", transition.Namespace, transition.Time.Format("Mon 2006-01-02 15:04 MST"), transition.Transition, transition.Rule)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/input"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+)
+
+func runStatus(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("status")
+	stateBackend := fs.String("state_backend", shared.StateBackendConfigMap, "where the original replicas are stored (configmap, annotations or status)")
+	configMapName := fs.String("configmap_name", "downscaler-cm", "configmap name of the configmap state backend")
+	configMapNamespace := fs.String("configmap_namespace", shared.DownscalerNamespace, "configmap namespace of the configmap state backend")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *stateBackend == shared.StateBackendMemory {
+		return fmt.Errorf("the memory state backend lives inside the downscaler process and can not be read")
+	}
+
+	c, err := opts.clients()
+	if err != nil {
+		return err
+	}
+
+	store, err := state.New(&input.FromArgs{
+		StateBackend:       *stateBackend,
+		ConfigMapName:      *configMapName,
+		ConfigMapNamespace: *configMapNamespace,
+	}, c.client, c.dynamicClient, nil, opts.name)
+	if err != nil {
+		return err
+	}
+
+	states, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Namespace < states[j].Namespace })
+
+	namespaces := make([]string, len(states))
+	for i, namespaceState := range states {
+		namespaces[i] = namespaceState.Namespace
+	}
+	overrides := c.kubernetes.GetNamespaceOverrides(ctx, namespaces)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tORIGINAL REPLICAS\tDOWNSCALED AT\tDOWNSCALED BY\tOVERRIDE")
+	for _, namespaceState := range states {
+		override := "-"
+		if o, found := overrides[namespaceState.Namespace]; found && o.Active(time.Now()) {
+			override = o.String()
+		}
+		for _, workload := range namespaceState.Apps.State {
+			if workload.Phase != shared.PhaseDownscaled {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				namespaceState.Namespace,
+				workload.Kind,
+				workload.Name,
+				workload.OriginalReplicas,
+				workload.ScaledAt.Format(time.RFC3339),
+				workload.ScaledBy,
+				override,
+			)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func runValidate(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("validate")
+	offline := fs.Bool("offline", false, "skip the checks that need the cluster, such as the namespace existence")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	policy, err := opts.policy(ctx)
+	if err != nil {
+		return err
+	}
+
+	errs := validation.Policy(policy)
+
+	if !*offline {
+		missing, err := missingNamespaces(ctx, opts, policy)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, missing...)
+	}
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+		return fmt.Errorf("%d validation error(s)", len(errs))
+	}
+
+	fmt.Println("the downscaler policy is valid")
+	return nil
+}
+
+func missingNamespaces(ctx context.Context, opts *options, policy *shared.DownscalerPolicy) (field.ErrorList, error) {
+	c, err := opts.clients()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]struct{})
+	for _, namespace := range c.kubernetes.GetNamespaces(ctx) {
+		existing[namespace] = struct{}{}
+	}
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("not possible to list the namespaces, use --offline to skip this check")
+	}
+
+	missing := field.ErrorList{}
+	for i, rule := range policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules {
+		for j, namespace := range rule.Namespaces {
+			if namespace == shared.Unspecified {
+				continue
+			}
+			if _, found := existing[namespace]; !found {
+				missing = append(missing, field.NotFound(validation.NamespacePath(policy, i, j), namespace))
+			}
+		}
+	}
+	return missing, nil
+}
@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"log/slog"
+	"os"
+	"time"
 
-	"github.com/adalbertjnr/downscaler/common"
 	"github.com/adalbertjnr/downscaler/core"
+	"github.com/adalbertjnr/downscaler/events"
+	"github.com/adalbertjnr/downscaler/health"
 	"github.com/adalbertjnr/downscaler/input"
 	"github.com/adalbertjnr/downscaler/kas"
 	"github.com/adalbertjnr/downscaler/kubeclient"
 	"github.com/adalbertjnr/downscaler/log"
+	"github.com/adalbertjnr/downscaler/metrics"
 	"github.com/adalbertjnr/downscaler/scheduler"
+	"github.com/adalbertjnr/downscaler/server"
 	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	"github.com/adalbertjnr/downscaler/status"
 	"github.com/adalbertjnr/downscaler/watcher"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"github.com/adalbertjnr/downscaler/webhook"
 )
 
 func main() {
@@ -20,49 +28,106 @@ func main() {
 
 	log.NewLogger(args.TimeZone)
 
-	client, err := kubeclient.NewClientOrDie()
+	config, err := kubeclient.NewConfig(kubeclient.Options{
+		Kubeconfig: args.Kubeconfig,
+		Context:    args.KubeContext,
+		UserAgent:  shared.UserAgent,
+		QPS:        float32(args.KubeAPIQPS),
+		Burst:      args.KubeAPIBurst,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	dynamicClient, err := kubeclient.NewDynamicClientOrDie()
+	client, err := kubeclient.NewClientForConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
+	dynamicClient, err := kubeclient.NewDynamicClientForConfig(config)
 	if err != nil {
 		panic(err)
 	}
 
 	ctx := context.Background()
 
-	scm := schema.GroupVersionResource{
-		Version:  shared.Version,
-		Resource: shared.Resource,
-		Group:    shared.Group,
+	healthChecker := health.NewChecker(health.DefaultStallTimeout)
+
+	httpServer := server.New(args.MetricsAddress).
+		AddHandler("/metrics", metrics.Handler()).
+		AddHandler("/healthz", healthChecker.HealthzHandler()).
+		AddHandler("/readyz", healthChecker.ReadyzHandler())
+	go httpServer.Run(ctx)
+
+	if args.WebhookAddress != "" {
+		webhookServer := server.New(args.WebhookAddress).
+			AddTLS(args.WebhookCertDir).
+			AddHandler("/validate", webhook.NewHandler())
+		go webhookServer.Run(ctx)
 	}
 
-	kubeApiSvc := kas.NewKubernetes(client, dynamicClient)
+	eventRecorder := events.NewRecorder(client)
+	defer eventRecorder.Shutdown()
 
-	policyData, err := kubeApiSvc.GetDownscalerData(ctx, scm)
-	if err != nil {
+	cache := kas.NewCache(client, dynamicClient, args.ConfigMapNamespace, kas.DefaultCacheResync)
+	if err := cache.Start(ctx); err != nil {
 		panic(err)
 	}
 
-	cmMetadata := shared.Metadata{
-		Name: policyData.Metadata.Name,
-	}
+	kubeApiSvc := kas.NewKubernetes(client, dynamicClient).
+		AddEventRecorder(eventRecorder).
+		AddCache(cache)
 
-	watch := watcher.New()
-	go watch.DownscalerKind(ctx, cmMetadata, kubeApiSvc)
+	if args.DryRun {
+		slog.Warn("dry run", "status", "enabled", "state backend", shared.StateBackendMemory, "reason", "no workload or state will be changed")
+		args.StateBackend = shared.StateBackendMemory
+	}
 
-	currentTz := common.RetrieveTzFromData(policyData)
+	newScheduler := func(ctx context.Context, policyName string) (*scheduler.Scheduler, error) {
+		stateStore, err := state.New(args, client, dynamicClient, cache, policyName)
+		if err != nil {
+			return nil, err
+		}
+
+		if args.RunUpscaling {
+			if err := state.Migrate(ctx, stateStore); err != nil {
+				slog.Error("state migration", "name", policyName, "error", err)
+			}
+		}
+
+		statusReporter := status.NewReporter(kubeApiSvc, policyName)
+		go statusReporter.Run(ctx)
+
+		var kubernetesSvc kas.Kubernetes = kubeApiSvc
+		if args.DryRun {
+			kubernetesSvc = kas.NewDryRunKubernetes(kubeApiSvc).
+				AddStatusReporter(statusReporter)
+		}
+
+		return scheduler.NewScheduler().
+			MustAddTimezoneLocation(time.UTC.String()).
+			AddContext(ctx).
+			AddKubeApiSvc(kubernetesSvc).
+			AddStateStore(stateStore).
+			AddStatusReporter(statusReporter).
+			AddEventRecorder(eventRecorder).
+			AddHealthChecker(healthChecker).
+			AddInput(args).
+			AddPolicyName(policyName), nil
+	}
 
-	schedulerSvc := scheduler.NewScheduler().
-		MustAddTimezoneLocation(currentTz).
-		AddKubeApiSvc(kubeApiSvc).
-		AddInput(args)
+	watch := watcher.New().
+		AddHealthChecker(healthChecker)
 
-	svc := core.NewController(ctx, kubeApiSvc, schedulerSvc, policyData, watch, args)
+	svc := core.NewController(ctx, kubeApiSvc, newScheduler, watch, args).
+		AddHealthChecker(healthChecker).
+		AddLeaderElection(client)
 
 	go svc.HandleSignals()
 
-	svc.ValidateConfigMapInitialization()
-	svc.StartDownscaler()
+	if err := svc.Run(); err != nil {
+		slog.Error("the downscaler did not shut down gracefully", "err", err)
+		eventRecorder.Shutdown()
+		os.Exit(1)
+	}
 }
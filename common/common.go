@@ -28,11 +28,22 @@ func UnmarshalDataPolicy(cm interface{}, data interface{}) error {
 		if err != nil {
 			return err
 		}
+		if v.GetKind() == shared.NamespaceDownscalerKind {
+			namespacePolicy := &shared.NamespaceDownscalerPolicy{}
+			if err := yaml.Unmarshal([]byte(jsonData), namespacePolicy); err != nil {
+				return err
+			}
+			*data.(*shared.DownscalerPolicy) = *namespacePolicy.DownscalerPolicy()
+			return nil
+		}
 		return yaml.Unmarshal([]byte(jsonData), data.(*shared.DownscalerPolicy))
 	}
 	return fmt.Errorf("error with the downscalercrd data")
 }
 
 func RetrieveTzFromData(data *shared.DownscalerPolicy) string {
+	if data == nil {
+		return ""
+	}
 	return strings.TrimSpace(data.Spec.ExecutionOpts.Time.TimeZone)
 }
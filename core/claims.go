@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+type policyClaims struct {
+	lost      map[string]struct{}
+	claimed   map[string]struct{}
+	conflicts []string
+}
+
+func (p policyClaims) fingerprint(policy *shared.DownscalerPolicy) string {
+	return fmt.Sprintf("%s/%d/%v/%v", policy.Metadata.UID, policy.Metadata.Generation, sortedKeys(p.lost), sortedKeys(p.claimed))
+}
+
+func (p policyClaims) effective(policy *shared.DownscalerPolicy) *shared.DownscalerPolicy {
+	effective := *policy
+	if len(p.lost) == 0 {
+		return &effective
+	}
+
+	rules := make([]shared.Rule, 0)
+	for _, rule := range policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules {
+		namespaces := make([]string, 0, len(rule.Namespaces))
+		for _, namespace := range rule.Namespaces {
+			if _, lost := p.lost[namespace]; !lost {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+		if len(namespaces) == 0 {
+			continue
+		}
+		rule.Namespaces = namespaces
+		rules = append(rules, rule)
+	}
+	effective.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = rules
+	return &effective
+}
+
+func resolveClaims(policies map[string]*shared.DownscalerPolicy) map[string]policyClaims {
+	ordered := make([]*shared.DownscalerPolicy, 0, len(policies))
+	for _, policy := range policies {
+		ordered = append(ordered, policy)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if namespaced(ordered[i]) != namespaced(ordered[j]) {
+			return !namespaced(ordered[i])
+		}
+		if ordered[i].Metadata.CreationTimestamp != ordered[j].Metadata.CreationTimestamp {
+			return ordered[i].Metadata.CreationTimestamp < ordered[j].Metadata.CreationTimestamp
+		}
+		return ordered[i].Key() < ordered[j].Key()
+	})
+
+	excludedBy := make(map[string]string)
+	for _, policy := range ordered {
+		if namespaced(policy) {
+			continue
+		}
+		for _, namespace := range policy.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Values {
+			if _, found := excludedBy[namespace]; !found {
+				excludedBy[namespace] = policyName(policy)
+			}
+		}
+	}
+
+	owners := make(map[string]*shared.DownscalerPolicy)
+	claims := make(map[string]policyClaims, len(ordered))
+	for _, policy := range ordered {
+		key := policy.Key()
+		claim := policyClaims{lost: make(map[string]struct{}), claimed: make(map[string]struct{})}
+
+		for _, rule := range policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules {
+			for _, namespace := range rule.Namespaces {
+				if _, reported := claim.lost[namespace]; reported {
+					continue
+				}
+
+				if excluder, excluded := excludedBy[namespace]; excluded && namespaced(policy) {
+					claim.lost[namespace] = struct{}{}
+					claim.conflicts = append(claim.conflicts, fmt.Sprintf("namespace %s is excluded by the %s and was ignored", namespace, excluder))
+					continue
+				}
+
+				owner, found := owners[namespace]
+				if !found || owner.Key() == key {
+					owners[namespace] = policy
+					continue
+				}
+
+				claim.lost[namespace] = struct{}{}
+				claim.conflicts = append(claim.conflicts, fmt.Sprintf("namespace %s is owned by the %s and was ignored", namespace, policyName(owner)))
+
+				ownerClaim := claims[owner.Key()]
+				ownerClaim.conflicts = append(ownerClaim.conflicts, fmt.Sprintf("namespace %s is also claimed by the %s", namespace, policyName(policy)))
+				claims[owner.Key()] = ownerClaim
+			}
+		}
+		claims[key] = claim
+	}
+
+	for key, claim := range claims {
+		for namespace, owner := range owners {
+			if owner.Key() != key && namespace != shared.Unspecified {
+				claim.claimed[namespace] = struct{}{}
+			}
+		}
+		sort.Strings(claim.conflicts)
+		claims[key] = claim
+	}
+	return claims
+}
+
+func namespaced(policy *shared.DownscalerPolicy) bool {
+	return policy.Kind == shared.NamespaceDownscalerKind
+}
+
+func policyName(policy *shared.DownscalerPolicy) string {
+	kind := policy.Kind
+	if kind == "" {
+		kind = shared.DownscalerKind
+	}
+	return kind + " " + policy.Key()
+}
+
+func sortedKeys(set map[string]struct{}) string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+func newPolicy(name, created string, namespaces ...[]string) *shared.DownscalerPolicy {
+	policy := &shared.DownscalerPolicy{}
+	policy.Metadata.Name = name
+	policy.Metadata.CreationTimestamp = created
+	for _, ruleNamespaces := range namespaces {
+		policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = append(
+			policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules,
+			shared.Rule{Namespaces: ruleNamespaces, WithCron: "01:30-14:50"},
+		)
+	}
+	return policy
+}
+
+func newNamespacePolicy(namespace, name, created string) *shared.DownscalerPolicy {
+	policy := &shared.NamespaceDownscalerPolicy{}
+	policy.Metadata.Name = name
+	policy.Metadata.Namespace = namespace
+	policy.Metadata.CreationTimestamp = created
+	policy.Spec.WithCron = "08:00-20:00"
+	return policy.DownscalerPolicy()
+}
+
+func TestResolveClaims(t *testing.T) {
+	tests := []struct {
+		name            string
+		policies        []*shared.DownscalerPolicy
+		policy          string
+		expectedLost    string
+		expectedClaimed string
+		expectedRules   int
+		conflicts       int
+	}{
+		{
+			name: "Disjoint policies",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("team-a", "2024-06-01T00:00:00Z", []string{"nginx-1"}),
+				newPolicy("team-b", "2024-06-02T00:00:00Z", []string{"nginx-2"}, []string{shared.Unspecified}),
+			},
+			policy:          "team-b",
+			expectedLost:    "",
+			expectedClaimed: "nginx-1",
+			expectedRules:   2,
+			conflicts:       0,
+		},
+		{
+			name: "Newer policy loses the overlapping namespace",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("team-a", "2024-06-01T00:00:00Z", []string{"nginx-1", "nginx-2"}),
+				newPolicy("team-b", "2024-06-02T00:00:00Z", []string{"nginx-2", "nginx-3"}),
+			},
+			policy:          "team-b",
+			expectedLost:    "nginx-2",
+			expectedClaimed: "nginx-1,nginx-2",
+			expectedRules:   1,
+			conflicts:       1,
+		},
+		{
+			name: "Older policy keeps the overlapping namespace and reports it",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("team-a", "2024-06-01T00:00:00Z", []string{"nginx-1", "nginx-2"}),
+				newPolicy("team-b", "2024-06-02T00:00:00Z", []string{"nginx-2"}),
+			},
+			policy:          "team-a",
+			expectedLost:    "",
+			expectedClaimed: "",
+			expectedRules:   1,
+			conflicts:       1,
+		},
+		{
+			name: "Newer policy loses every rule",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("team-a", "2024-06-01T00:00:00Z", []string{shared.Unspecified}),
+				newPolicy("team-b", "2024-06-02T00:00:00Z", []string{shared.Unspecified}),
+			},
+			policy:          "team-b",
+			expectedLost:    shared.Unspecified,
+			expectedClaimed: "",
+			expectedRules:   0,
+			conflicts:       1,
+		},
+		{
+			name: "Cluster unspecified leaves the namespace to the NamespaceDownscaler",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("downscaler", "2024-06-01T00:00:00Z", []string{shared.Unspecified}),
+				newNamespacePolicy("team-x", "nightly", "2024-06-02T00:00:00Z"),
+			},
+			policy:          "downscaler",
+			expectedLost:    "",
+			expectedClaimed: "team-x",
+			expectedRules:   1,
+			conflicts:       0,
+		},
+		{
+			name: "Cluster rule wins over an older NamespaceDownscaler",
+			policies: []*shared.DownscalerPolicy{
+				newPolicy("downscaler", "2024-06-02T00:00:00Z", []string{"team-x"}),
+				newNamespacePolicy("team-x", "nightly", "2024-06-01T00:00:00Z"),
+			},
+			policy:          "team-x/nightly",
+			expectedLost:    "team-x",
+			expectedClaimed: "team-x",
+			expectedRules:   0,
+			conflicts:       1,
+		},
+		{
+			name: "Cluster exclude wins over the NamespaceDownscaler",
+			policies: func() []*shared.DownscalerPolicy {
+				cluster := newPolicy("downscaler", "2024-06-01T00:00:00Z", []string{shared.Unspecified})
+				cluster.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Values = []string{"kube-system"}
+				return []*shared.DownscalerPolicy{cluster, newNamespacePolicy("kube-system", "nightly", "2024-06-02T00:00:00Z")}
+			}(),
+			policy:          "kube-system/nightly",
+			expectedLost:    "kube-system",
+			expectedClaimed: "",
+			expectedRules:   0,
+			conflicts:       1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies := make(map[string]*shared.DownscalerPolicy)
+			for _, policy := range tt.policies {
+				policies[policy.Key()] = policy
+			}
+
+			claim := resolveClaims(policies)[tt.policy]
+			if lost := sortedKeys(claim.lost); lost != tt.expectedLost {
+				t.Errorf("lost = %q; expected %q", lost, tt.expectedLost)
+			}
+			if claimed := sortedKeys(claim.claimed); claimed != tt.expectedClaimed {
+				t.Errorf("claimed = %q; expected %q", claimed, tt.expectedClaimed)
+			}
+			if len(claim.conflicts) != tt.conflicts {
+				t.Errorf("conflicts = %v; expected %d", claim.conflicts, tt.conflicts)
+			}
+
+			rules := claim.effective(policies[tt.policy]).Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules
+			if len(rules) != tt.expectedRules {
+				t.Errorf("len(effective rules) = %d; expected %d", len(rules), tt.expectedRules)
+			}
+		})
+	}
+}
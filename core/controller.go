@@ -2,12 +2,17 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/adalbertjnr/downscaler/common"
+	"github.com/adalbertjnr/downscaler/health"
 	"github.com/adalbertjnr/downscaler/input"
 	"github.com/adalbertjnr/downscaler/kas"
 	"github.com/adalbertjnr/downscaler/scheduler"
@@ -16,38 +21,51 @@ import (
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 )
 
+type SchedulerFactory func(ctx context.Context, policyName string) (*scheduler.Scheduler, error)
+
+type managedPolicy struct {
+	scheduler *scheduler.Scheduler
+	cancelFn  context.CancelFunc
+	applied   string
+	conflicts string
+}
+
 type Controller struct {
-	client            kas.Kubernetes
-	scheduler         scheduler.Scheduler
-	rtObjectch        chan runtime.Object
-	cmObjectch        chan shared.DownscalerPolicy
-	ctx               context.Context
-	cancelFn          context.CancelFunc
-	initialCronConfig *shared.DownscalerPolicy
-	watch             *watcher.Watcher
-	input             *input.FromArgs
+	client       kas.Kubernetes
+	newScheduler SchedulerFactory
+	policies     map[string]*shared.DownscalerPolicy
+	schedulers   map[string]*managedPolicy
+	cmObjectch   chan shared.DownscalerPolicy
+	deletedch    chan shared.DownscalerPolicy
+	ctx          context.Context
+	cancelFn     context.CancelFunc
+	watch        *watcher.Watcher
+	health       *health.Checker
+	leaseClient  kubernetes.Interface
+	input        *input.FromArgs
 }
 
 func NewController(ctx context.Context,
 	client kas.Kubernetes,
-	scheduler *scheduler.Scheduler,
-	initialCronConfig *shared.DownscalerPolicy,
+	newScheduler SchedulerFactory,
 	watch *watcher.Watcher,
 	input *input.FromArgs,
 ) *Controller {
 	context, cancel := context.WithCancel(ctx)
 	return &Controller{
-		client:            client,
-		scheduler:         *scheduler,
-		initialCronConfig: initialCronConfig,
-		rtObjectch:        make(chan runtime.Object, 1),
-		cmObjectch:        make(chan shared.DownscalerPolicy, 1),
-		ctx:               context,
-		cancelFn:          cancel,
-		watch:             watch,
-		input:             input,
+		client:       client,
+		newScheduler: newScheduler,
+		policies:     make(map[string]*shared.DownscalerPolicy),
+		schedulers:   make(map[string]*managedPolicy),
+		cmObjectch:   make(chan shared.DownscalerPolicy, 1),
+		deletedch:    make(chan shared.DownscalerPolicy, 1),
+		ctx:          context,
+		cancelFn:     cancel,
+		watch:        watch,
+		input:        input,
 	}
 }
 
@@ -55,44 +73,147 @@ func (c *Controller) updateNewCronLoop() {
 	for {
 		select {
 		case cmDataPolicy := <-c.cmObjectch:
-			c.scheduler.AddSchedulerDetails(&cmDataPolicy)
+			c.policies[cmDataPolicy.Key()] = &cmDataPolicy
+			c.reconcilePolicies()
+		case deleted := <-c.deletedch:
+			c.removePolicy(&deleted)
+			c.reconcilePolicies()
 		case <-c.ctx.Done():
 			return
 		}
 	}
 }
 
-func (c *Controller) StartDownscaler() {
+func (c *Controller) reconcilePolicies() {
+	claims := resolveClaims(c.policies)
+
+	for name, policy := range c.policies {
+		managed, found := c.schedulers[name]
+		if !found {
+			ctx, cancel := context.WithCancel(c.ctx)
+			scheduler, err := c.newScheduler(ctx, name)
+			if err != nil {
+				cancel()
+				slog.Error("scheduler", "kind", "downscaler", "name", name, "status", "not created", "err", err)
+				continue
+			}
+			go scheduler.StartScheduler()
+
+			managed = &managedPolicy{scheduler: scheduler, cancelFn: cancel}
+			c.schedulers[name] = managed
+		}
+
+		claim := claims[name]
+		if fingerprint := claim.fingerprint(policy); fingerprint != managed.applied {
+			managed.applied = fingerprint
+			c.applyPolicy(managed.scheduler, policy, claim)
+		}
+
+		if conflicts := strings.Join(claim.conflicts, "; "); conflicts != managed.conflicts || !found {
+			managed.conflicts = conflicts
+			managed.scheduler.ReportConflicts(policy, claim.conflicts)
+		}
+	}
+}
+
+func (c *Controller) applyPolicy(scheduler *scheduler.Scheduler, policy *shared.DownscalerPolicy, claim policyClaims) {
+	effective := claim.effective(policy)
+	scheduler.AddClaimedNamespaces(claim.claimed)
+
+	if len(effective.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules) == 0 &&
+		len(policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules) > 0 {
+		slog.Warn("scheduler", "kind", policy.Kind, "name", policy.Key(), "status", "suspended", "reason", "every namespace is owned by another Downscaler")
+		scheduler.StopTasks()
+		return
+	}
+	scheduler.AddSchedulerDetails(effective)
+}
+
+func (c *Controller) removePolicy(policy *shared.DownscalerPolicy) {
+	name := policy.Key()
+	delete(c.policies, name)
+
+	managed, found := c.schedulers[name]
+	if !found {
+		return
+	}
+	delete(c.schedulers, name)
+
+	managed.scheduler.RemoveSchedulerDetails(policy)
+	managed.scheduler.Stop()
+	managed.cancelFn()
+}
+
+func (c *Controller) StartDownscaler() error {
+	go c.watch.DownscalerKind(c.ctx, shared.Metadata{Resource: shared.Resource}, c.client)
+	go c.watch.DownscalerKind(c.ctx, shared.Metadata{Resource: shared.NamespacedResource}, c.client)
 	go c.ReceiveNewConfigMapData()
-	go c.updateNewCronLoop()
-	go c.scheduler.StartScheduler()
 
-	slog.Info("downscaler initialization", "status", "initialized")
+	slog.Info("downscaler initialization", "status", "initialized", "action", "waiting for the Downscaler objects")
+
+	c.updateNewCronLoop()
+	slog.Warn("the downscaler is shutting down gracefully...", "timeout", c.input.ShutdownTimeout.String())
+
+	return c.drainSchedulers()
+}
+
+func (c *Controller) drainSchedulers() error {
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Int32
+	)
+
+	for name, managed := range c.schedulers {
+		wg.Add(1)
+		go func(name string, managed *managedPolicy) {
+			defer wg.Done()
+			if err := managed.scheduler.Drain(c.input.ShutdownTimeout); err != nil {
+				slog.Error("scheduler", "name", name, "status", "not drained", "err", err)
+				failed.Add(1)
+			}
+			managed.scheduler.Stop()
+			managed.cancelFn()
+		}(name, managed)
+	}
+	wg.Wait()
 
-	c.cmObjectch <- *c.initialCronConfig
-	<-c.ctx.Done()
-	slog.Warn("the downscaler is shutting down gracefully...")
+	if failed.Load() > 0 {
+		return fmt.Errorf("%d scheduler(s) did not finish the in-flight scaling within %s", failed.Load(), c.input.ShutdownTimeout)
+	}
+	slog.Info("the downscaler finished the in-flight scaling", "schedulers", len(c.schedulers))
+	return nil
 }
 
 func (c *Controller) ReceiveNewConfigMapData() {
 	for {
 		select {
 		case object := <-c.watch.RtObjectch:
-			cm, converted := object.(*unstructured.Unstructured)
-			if converted {
-				data := &shared.DownscalerPolicy{}
-				err := common.UnmarshalDataPolicy(cm, data)
-				if err != nil {
-					slog.Error("error unmarshaling the yaml data policy", "error", err.Error())
-				}
+			if data, converted := unmarshalDownscaler(object); converted {
 				c.cmObjectch <- *data
 			}
+		case object := <-c.watch.Deletedch:
+			if data, converted := unmarshalDownscaler(object); converted {
+				c.deletedch <- *data
+			}
 		case <-c.ctx.Done():
 			return
 		}
 	}
 }
 
+func unmarshalDownscaler(object runtime.Object) (*shared.DownscalerPolicy, bool) {
+	cm, converted := object.(*unstructured.Unstructured)
+	if !converted {
+		return nil, false
+	}
+
+	data := &shared.DownscalerPolicy{}
+	if err := common.UnmarshalDataPolicy(cm, data); err != nil {
+		slog.Error("error unmarshaling the yaml data policy", "error", err.Error())
+	}
+	return data, true
+}
+
 func (c *Controller) HandleSignals() {
 	sigch := make(chan os.Signal, 1)
 	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM)
@@ -103,7 +224,7 @@ func (c *Controller) HandleSignals() {
 }
 
 func (c *Controller) ValidateConfigMapInitialization() {
-	if c.input.RunUpscaling {
+	if c.input.RunUpscaling && c.input.StateBackend == shared.StateBackendConfigMap {
 		cm := c.client.ListConfigMap(c.ctx, c.input.ConfigMapName, c.input.ConfigMapNamespace)
 		if cm != nil {
 			return
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"github.com/adalbertjnr/downscaler/health"
+	"github.com/adalbertjnr/downscaler/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func (c *Controller) AddHealthChecker(checker *health.Checker) *Controller {
+	c.health = checker
+	return c
+}
+
+func (c *Controller) AddLeaderElection(client kubernetes.Interface) *Controller {
+	c.leaseClient = client
+	return c
+}
+
+func (c *Controller) Run() error {
+	if !c.input.LeaderElection.Enabled || c.leaseClient == nil {
+		return c.lead()
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		slog.Error("leader election", "status", "hostname not found", "err", err)
+		os.Exit(1)
+	}
+
+	opts := c.input.LeaderElection
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      opts.LeaseName,
+			Namespace: opts.Namespace,
+		},
+		Client:     c.leaseClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	c.health.Standby()
+	metrics.Leader(false)
+	slog.Info("leader election", "lease", opts.Namespace+"/"+opts.LeaseName, "identity", identity, "status", "standby")
+
+	var (
+		leading     atomic.Bool
+		shutdownErr error
+	)
+
+	electionCtx, cancelElection := context.WithCancel(context.WithoutCancel(c.ctx))
+	defer cancelElection()
+	go func() {
+		<-c.ctx.Done()
+		if !leading.Load() {
+			cancelElection()
+		}
+	}()
+
+	leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            opts.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				leading.Store(true)
+				slog.Info("leader election", "lease", opts.Namespace+"/"+opts.LeaseName, "identity", identity, "status", "leading")
+				shutdownErr = c.lead()
+				cancelElection()
+			},
+			OnStoppedLeading: func() {
+				metrics.Leader(false)
+				if c.ctx.Err() != nil {
+					slog.Info("leader election", "lease", opts.Namespace+"/"+opts.LeaseName, "identity", identity, "status", "released")
+					return
+				}
+				slog.Error("leader election", "lease", opts.Namespace+"/"+opts.LeaseName, "identity", identity, "status", "lost", "reason", "the lease was not renewed in time")
+				os.Exit(1)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					slog.Info("leader election", "lease", opts.Namespace+"/"+opts.LeaseName, "leader", current, "status", "standby")
+				}
+			},
+		},
+	})
+	return shutdownErr
+}
+
+func (c *Controller) lead() error {
+	c.health.Leading()
+	metrics.Leader(true)
+
+	c.ValidateConfigMapInitialization()
+	return c.StartDownscaler()
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	component = "downscaler"
+
+	ReasonDownscaled     = "Downscaled"
+	ReasonUpscaled       = "Upscaled"
+	ReasonScaleFailed    = "ScaleFailed"
+	ReasonPolicyApplied  = "PolicyApplied"
+	ReasonPolicyInvalid  = "PolicyInvalid"
+	ReasonPolicyConflict = "PolicyConflict"
+	ReasonDriftCorrected = "DriftCorrected"
+)
+
+type Recorder struct {
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+}
+
+func NewRecorder(client kubernetes.Interface) *Recorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+
+	return &Recorder{
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component}),
+	}
+}
+
+func NewRecorderFrom(recorder record.EventRecorder) *Recorder {
+	return &Recorder{recorder: recorder}
+}
+
+func (r *Recorder) Shutdown() {
+	if r == nil || r.broadcaster == nil {
+		return
+	}
+	r.broadcaster.Shutdown()
+}
+
+func (r *Recorder) Scaled(deployment *v1.Deployment, from, to int32, rule string) {
+	if r == nil {
+		return
+	}
+	reason := scaleReason(from, to)
+	r.recorder.Eventf(deployment, corev1.EventTypeNormal, reason, "%s from %d to %d by rule %s", reason, from, to, rule)
+}
+
+func (r *Recorder) ScaleFailed(deployment *v1.Deployment, from, to int32, rule string, err error) {
+	if r == nil {
+		return
+	}
+	verb := "upscale"
+	if to < from {
+		verb = "downscale"
+	}
+	r.recorder.Eventf(deployment, corev1.EventTypeWarning, ReasonScaleFailed, "Failed to %s from %d to %d by rule %s: %v", verb, from, to, rule, err)
+}
+
+func (r *Recorder) DriftCorrected(deployment *v1.Deployment, replicas, originalReplicas int32, redeployed bool, rule string) {
+	if r == nil {
+		return
+	}
+	if redeployed {
+		r.recorder.Eventf(deployment, corev1.EventTypeWarning, ReasonDriftCorrected, "Redeployed with %d replicas during the down window of rule %s, downscaling again and saving %d as the original replicas", replicas, rule, originalReplicas)
+		return
+	}
+	r.recorder.Eventf(deployment, corev1.EventTypeWarning, ReasonDriftCorrected, "Scaled to %d replicas during the down window of rule %s, downscaling again and keeping %d as the original replicas", replicas, rule, originalReplicas)
+}
+
+func (r *Recorder) NamespaceScaled(namespace, reason string, workloads int, rule string) {
+	if r == nil || workloads == 0 {
+		return
+	}
+	r.recorder.Eventf(namespaceReference(namespace), corev1.EventTypeNormal, reason, "%s %d deployment(s) by rule %s", reason, workloads, rule)
+}
+
+func (r *Recorder) PolicyApplied(policy *shared.DownscalerPolicy, rules int) {
+	if r == nil {
+		return
+	}
+	r.recorder.Eventf(downscalerReference(policy), corev1.EventTypeNormal, ReasonPolicyApplied, "Policy generation %d applied with %d rule(s)", policy.Metadata.Generation, rules)
+}
+
+func (r *Recorder) PolicyInvalid(policy *shared.DownscalerPolicy, errors []string) {
+	if r == nil {
+		return
+	}
+	r.recorder.Eventf(downscalerReference(policy), corev1.EventTypeWarning, ReasonPolicyInvalid, "Policy generation %d rejected: %s", policy.Metadata.Generation, strings.Join(errors, "; "))
+}
+
+func (r *Recorder) PolicyConflict(policy *shared.DownscalerPolicy, conflicts []string) {
+	if r == nil {
+		return
+	}
+	r.recorder.Eventf(downscalerReference(policy), corev1.EventTypeWarning, ReasonPolicyConflict, "Policy generation %d conflicts with other Downscalers: %s", policy.Metadata.Generation, strings.Join(conflicts, "; "))
+}
+
+func scaleReason(from, to int32) string {
+	if to < from {
+		return ReasonDownscaled
+	}
+	return ReasonUpscaled
+}
+
+func namespaceReference(namespace string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       namespace,
+	}
+}
+
+func downscalerReference(policy *shared.DownscalerPolicy) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: fmt.Sprintf("%s/%s", shared.Group, shared.Version),
+		Kind:       policy.Kind,
+		Namespace:  policy.Metadata.Namespace,
+		Name:       policy.Metadata.Name,
+		UID:        types.UID(policy.Metadata.UID),
+	}
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestScaleEvents(t *testing.T) {
+	deployment := &v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "nginx-2"}}
+	rule := "nginx-2 01:30-14:50"
+
+	tests := []struct {
+		name     string
+		emit     func(r *Recorder)
+		expected string
+	}{
+		{"Downscaled deployment", func(r *Recorder) { r.Scaled(deployment, 3, 0, rule) }, "Normal Downscaled Downscaled from 3 to 0 by rule nginx-2 01:30-14:50"},
+		{"Upscaled deployment", func(r *Recorder) { r.Scaled(deployment, 0, 3, rule) }, "Normal Upscaled Upscaled from 0 to 3 by rule nginx-2 01:30-14:50"},
+		{"Failed downscaling", func(r *Recorder) { r.ScaleFailed(deployment, 3, 0, rule, errors.New("forbidden")) }, "Warning ScaleFailed Failed to downscale from 3 to 0 by rule nginx-2 01:30-14:50: forbidden"},
+		{"Drift during the down window", func(r *Recorder) { r.DriftCorrected(deployment, 3, 2, false, rule) }, "Warning DriftCorrected Scaled to 3 replicas during the down window of rule nginx-2 01:30-14:50, downscaling again and keeping 2 as the original replicas"},
+		{"Redeployed during the down window", func(r *Recorder) { r.DriftCorrected(deployment, 4, 4, true, rule) }, "Warning DriftCorrected Redeployed with 4 replicas during the down window of rule nginx-2 01:30-14:50, downscaling again and saving 4 as the original replicas"},
+		{"Downscaled namespace", func(r *Recorder) { r.NamespaceScaled("nginx-2", ReasonDownscaled, 2, rule) }, "Normal Downscaled Downscaled 2 deployment(s) by rule nginx-2 01:30-14:50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := record.NewFakeRecorder(1)
+			tt.emit(NewRecorderFrom(fake))
+
+			select {
+			case event := <-fake.Events:
+				if event != tt.expected {
+					t.Errorf("event = %q; expected %q", event, tt.expected)
+				}
+			default:
+				t.Errorf("no event emitted; expected %q", tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultStallTimeout = time.Minute * 5
+
+var (
+	ErrPolicyNotLoaded     = errors.New("the downscaler policy was not loaded and validated yet")
+	ErrWatchNotEstablished = errors.New("the downscaler policy watch is not established")
+)
+
+type Checker struct {
+	mu             sync.Mutex
+	stallTimeout   time.Duration
+	now            func() time.Time
+	policies       map[string]struct{}
+	standby        bool
+	watches        map[string]*watchState
+	watchDownSince time.Time
+	taskDeadlines  map[string]time.Time
+}
+
+type watchState struct {
+	established bool
+	downSince   time.Time
+}
+
+func NewChecker(stallTimeout time.Duration) *Checker {
+	return &Checker{
+		stallTimeout:   stallTimeout,
+		now:            time.Now,
+		watchDownSince: time.Now(),
+		policies:       make(map[string]struct{}),
+		watches:        make(map[string]*watchState),
+		taskDeadlines:  make(map[string]time.Time),
+	}
+}
+
+func (c *Checker) PolicyApplied(policy string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policies[policy] = struct{}{}
+}
+
+func (c *Checker) PolicyRemoved(policy string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.policies, policy)
+}
+
+func (c *Checker) Standby() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.standby = true
+}
+
+func (c *Checker) Leading() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.standby = false
+	c.watchDownSince = c.now()
+	for _, watch := range c.watches {
+		watch.downSince = c.watchDownSince
+	}
+}
+
+func (c *Checker) WatchEstablished(resource string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watch(resource).established = true
+}
+
+func (c *Checker) WatchLost(resource string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	watch := c.watch(resource)
+	if watch.established {
+		watch.downSince = c.now()
+	}
+	watch.established = false
+}
+
+func (c *Checker) watch(resource string) *watchState {
+	watch, found := c.watches[resource]
+	if !found {
+		watch = &watchState{downSince: c.watchDownSince}
+		c.watches[resource] = watch
+	}
+	return watch
+}
+
+func (c *Checker) lostWatches() []string {
+	lost := make([]string, 0)
+	for resource, watch := range c.watches {
+		if !watch.established {
+			lost = append(lost, resource)
+		}
+	}
+	sort.Strings(lost)
+	return lost
+}
+
+func (c *Checker) Heartbeat(task string, grace time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.taskDeadlines[task] = c.now().Add(c.stallTimeout + grace)
+}
+
+func (c *Checker) TaskStopped(task string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.taskDeadlines, task)
+}
+
+func (c *Checker) Ready() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.standby {
+		return nil
+	}
+	if len(c.policies) == 0 {
+		return ErrPolicyNotLoaded
+	}
+	if len(c.watches) == 0 {
+		return ErrWatchNotEstablished
+	}
+	if lost := c.lostWatches(); len(lost) > 0 {
+		return fmt.Errorf("%w: %s", ErrWatchNotEstablished, strings.Join(lost, ", "))
+	}
+	return nil
+}
+
+func (c *Checker) Live() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if !c.standby {
+		if downSince, down := c.everyWatchDownSince(); down && now.Sub(downSince) > c.stallTimeout {
+			return fmt.Errorf("%w for %s", ErrWatchNotEstablished, now.Sub(downSince).Round(time.Second))
+		}
+	}
+
+	stalled := make([]string, 0)
+	for task, deadline := range c.taskDeadlines {
+		if now.After(deadline) {
+			stalled = append(stalled, task)
+		}
+	}
+	if len(stalled) > 0 {
+		sort.Strings(stalled)
+		return fmt.Errorf("scheduler task(s) stalled: %s", strings.Join(stalled, "; "))
+	}
+	return nil
+}
+
+// a restart only helps when every watch is down, a single failing resource is
+// reported by the readiness without restarting the watches that still work
+func (c *Checker) everyWatchDownSince() (time.Time, bool) {
+	downSince := c.watchDownSince
+	for _, watch := range c.watches {
+		if watch.established {
+			return time.Time{}, false
+		}
+		if watch.downSince.After(downSince) {
+			downSince = watch.downSince
+		}
+	}
+	return downSince, true
+}
+
+func (c *Checker) HealthzHandler() http.Handler {
+	return probeHandler(c.Live)
+}
+
+func (c *Checker) ReadyzHandler() http.Handler {
+	return probeHandler(c.Ready)
+}
+
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+}
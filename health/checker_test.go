@@ -0,0 +1,108 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	start := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		setup     func(c *Checker, now *time.Time)
+		readyErr  error
+		liveError bool
+	}{
+		{"Startup before the policy and the watch", func(c *Checker, now *time.Time) {}, ErrPolicyNotLoaded, false},
+		{"Policy applied without the watch", func(c *Checker, now *time.Time) { c.PolicyApplied("downscaler") }, ErrWatchNotEstablished, false},
+		{"Policy applied and watch established", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.Heartbeat("nginx-2 01:30-14:50", 0)
+		}, nil, false},
+		{"Watch failing for longer than the stall timeout", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, ErrWatchNotEstablished, true},
+		{"One watch lost while the other is established", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.WatchEstablished("namespacedownscalers")
+			c.WatchLost("namespacedownscalers")
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, ErrWatchNotEstablished, false},
+		{"One watch failing while the other one recycles", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchLost("namespacedownscalers")
+			c.WatchLost("downscalers")
+			c.WatchEstablished("downscalers")
+		}, ErrWatchNotEstablished, false},
+		{"Every watch lost for longer than the stall timeout", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.WatchEstablished("namespacedownscalers")
+			c.WatchLost("downscalers")
+			c.WatchLost("namespacedownscalers")
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, ErrWatchNotEstablished, true},
+		{"Scheduler task stalled", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.Heartbeat("nginx-2 01:30-14:50", 0)
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, nil, true},
+		{"Scheduler task within the upscale spread grace", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.Heartbeat("nginx-2 01:30-14:50", time.Hour)
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, nil, false},
+		{"Stopped scheduler task", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.Heartbeat("nginx-2 01:30-14:50", 0)
+			c.TaskStopped("nginx-2 01:30-14:50")
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, nil, false},
+		{"One of the Downscalers deleted", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.PolicyApplied("team-a")
+			c.WatchEstablished("downscalers")
+			c.PolicyRemoved("team-a")
+		}, nil, false},
+		{"Standby replica waiting for the leadership", func(c *Checker, now *time.Time) {
+			c.Standby()
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+		}, nil, false},
+		{"Replica that just acquired the leadership", func(c *Checker, now *time.Time) {
+			c.Standby()
+			*now = now.Add(DefaultStallTimeout + time.Minute)
+			c.Leading()
+		}, ErrPolicyNotLoaded, false},
+		{"Downscaler deleted", func(c *Checker, now *time.Time) {
+			c.PolicyApplied("downscaler")
+			c.WatchEstablished("downscalers")
+			c.PolicyRemoved("downscaler")
+		}, ErrPolicyNotLoaded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			checker := NewChecker(DefaultStallTimeout)
+			checker.now = func() time.Time { return now }
+			checker.watchDownSince = start
+
+			tt.setup(checker, &now)
+
+			if err := checker.Ready(); !errors.Is(err, tt.readyErr) {
+				t.Errorf("Ready() = %v; expected %v", err, tt.readyErr)
+			}
+			if err := checker.Live(); (err != nil) != tt.liveError {
+				t.Errorf("Live() = %v; expected error %v", err, tt.liveError)
+			}
+		})
+	}
+}
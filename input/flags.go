@@ -2,25 +2,86 @@ package input
 
 import (
 	"flag"
+	"time"
 )
 
+type LeaderElection struct {
+	Enabled       bool
+	LeaseName     string
+	Namespace     string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
 type FromArgs struct {
 	ConfigMapName      string
 	ConfigMapNamespace string
 	TimeZone           string
+	StateBackend       string
+	MetricsAddress     string
+	WebhookAddress     string
+	WebhookCertDir     string
+	Kubeconfig         string
+	KubeContext        string
+	KubeAPIQPS         float64
+	KubeAPIBurst       int
+	ShutdownTimeout    time.Duration
+	LeaderElection     LeaderElection
 	RunUpscaling       bool
+	UpscaleOnDelete    bool
+	Enforce            bool
+	DryRun             bool
 }
 
 func FromEntrypoint() *FromArgs {
 	runUpscaling := flag.Bool("run_upscaling", false, "set true if should run upscaling")
+	upscaleOnDelete := flag.Bool("upscale_on_delete", true, "set true to upscale every downscaled workload when the Downscaler is deleted (requires run_upscaling)")
+	enforce := flag.Bool("enforce", false, "set true to downscale again the deployments scaled up or redeployed during the down window (requires run_upscaling)")
 	configMapName := flag.String("configmap_name", "downscaler-cm", "set the configmap name")
 	configMapNamespace := flag.String("configmap_namespace", "downscaler", "set the configmap namespace")
 	timezone := flag.String("timezone", "", "set the timezone")
+	stateBackend := flag.String("state_backend", "configmap", "set where the original replicas are stored (configmap, annotations, status or memory)")
+	metricsAddress := flag.String("metrics_address", ":8080", "set the address of the http server exposing /metrics, /healthz and /readyz")
+	webhookAddress := flag.String("webhook_address", "", "set the address of the https server exposing the /validate admission webhook (disabled when empty)")
+	webhookCertDir := flag.String("webhook_cert_dir", "/etc/downscaler/webhook-certs", "set the directory with the tls.crt and tls.key served by the admission webhook")
+	kubeconfig := flag.String("kubeconfig", "", "set the kubeconfig path to run outside the cluster (defaults to $KUBECONFIG, then the in-cluster config, then ~/.kube/config)")
+	kubeContext := flag.String("context", "", "set the kubeconfig context")
+	kubeAPIQPS := flag.Float64("kube_api_qps", 20, "set the maximum queries per second to the kubernetes api")
+	kubeAPIBurst := flag.Int("kube_api_burst", 30, "set the maximum burst of queries to the kubernetes api")
+	shutdownTimeout := flag.Duration("shutdown_timeout", 30*time.Second, "set how long the in-flight scaling has to finish on SIGTERM before it is aborted")
+	dryRun := flag.Bool("dry_run", false, "set true to log the actions the downscaler would take without scaling anything")
+	leaderElect := flag.Bool("leader_elect", false, "set true to run several replicas where only the lease holder runs the scheduler tasks")
+	leaderElectLeaseName := flag.String("leader_elect_lease_name", "downscaler-leader", "set the name of the leader election lease")
+	leaderElectNamespace := flag.String("leader_elect_namespace", "downscaler", "set the namespace of the leader election lease")
+	leaderElectLeaseDuration := flag.Duration("leader_elect_lease_duration", 15*time.Second, "set how long the standbys wait before taking over a lease that was not renewed")
+	leaderElectRenewDeadline := flag.Duration("leader_elect_renew_deadline", 10*time.Second, "set how long the leader retries renewing the lease before giving it up")
+	leaderElectRetryPeriod := flag.Duration("leader_elect_retry_period", 2*time.Second, "set the interval between lease acquire and renew attempts")
 	flag.Parse()
 	return &FromArgs{
 		RunUpscaling:       *runUpscaling,
+		UpscaleOnDelete:    *upscaleOnDelete,
+		Enforce:            *enforce,
 		ConfigMapName:      *configMapName,
 		ConfigMapNamespace: *configMapNamespace,
 		TimeZone:           *timezone,
+		StateBackend:       *stateBackend,
+		MetricsAddress:     *metricsAddress,
+		WebhookAddress:     *webhookAddress,
+		WebhookCertDir:     *webhookCertDir,
+		Kubeconfig:         *kubeconfig,
+		KubeContext:        *kubeContext,
+		KubeAPIQPS:         *kubeAPIQPS,
+		KubeAPIBurst:       *kubeAPIBurst,
+		ShutdownTimeout:    *shutdownTimeout,
+		DryRun:             *dryRun,
+		LeaderElection: LeaderElection{
+			Enabled:       *leaderElect,
+			LeaseName:     *leaderElectLeaseName,
+			Namespace:     *leaderElectNamespace,
+			LeaseDuration: *leaderElectLeaseDuration,
+			RenewDeadline: *leaderElectRenewDeadline,
+			RetryPeriod:   *leaderElectRetryPeriod,
+		},
 	}
 }
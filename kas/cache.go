@@ -0,0 +1,170 @@
+package kas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const DefaultCacheResync = 10 * time.Minute
+
+var (
+	DownscalerResource = schema.GroupVersionResource{
+		Group:    shared.Group,
+		Version:  shared.Version,
+		Resource: shared.Resource,
+	}
+	NamespaceDownscalerResource = schema.GroupVersionResource{
+		Group:    shared.Group,
+		Version:  shared.Version,
+		Resource: shared.NamespacedResource,
+	}
+)
+
+type Cache struct {
+	factory          informers.SharedInformerFactory
+	configMapFactory informers.SharedInformerFactory
+	dynamicFactory   dynamicinformer.DynamicSharedInformerFactory
+
+	namespaces  corelisters.NamespaceLister
+	deployments appslisters.DeploymentLister
+	configMaps  corelisters.ConfigMapLister
+	downscalers cache.GenericLister
+
+	configMapNamespace string
+	hasSynced          []cache.InformerSynced
+	synced             atomic.Bool
+}
+
+func NewCache(client kubernetes.Interface, dynamicClient dynamic.Interface, configMapNamespace string, resync time.Duration) *Cache {
+	factory := informers.NewSharedInformerFactory(client, resync)
+	configMapFactory := informers.NewSharedInformerFactoryWithOptions(client, resync, informers.WithNamespace(configMapNamespace))
+	dynamicFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resync)
+
+	namespaces := factory.Core().V1().Namespaces()
+	deployments := factory.Apps().V1().Deployments()
+	configMaps := configMapFactory.Core().V1().ConfigMaps()
+	downscalers := dynamicFactory.ForResource(DownscalerResource)
+
+	return &Cache{
+		factory:          factory,
+		configMapFactory: configMapFactory,
+		dynamicFactory:   dynamicFactory,
+		namespaces:       namespaces.Lister(),
+		deployments:      deployments.Lister(),
+		configMaps:       configMaps.Lister(),
+		downscalers:      downscalers.Lister(),
+
+		configMapNamespace: configMapNamespace,
+		hasSynced: []cache.InformerSynced{
+			namespaces.Informer().HasSynced,
+			deployments.Informer().HasSynced,
+			configMaps.Informer().HasSynced,
+			downscalers.Informer().HasSynced,
+		},
+	}
+}
+
+func (c *Cache) Start(ctx context.Context) error {
+	c.factory.Start(ctx.Done())
+	c.configMapFactory.Start(ctx.Done())
+	c.dynamicFactory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.hasSynced...) {
+		return fmt.Errorf("failed to sync the informers cache")
+	}
+
+	c.synced.Store(true)
+	slog.Info("cache", "resources", "namespaces, deployments, configmaps, downscalers", "status", "synced")
+	return nil
+}
+
+func (c *Cache) Synced() bool {
+	return c != nil && c.synced.Load()
+}
+
+func (c *Cache) Namespaces() ([]string, error) {
+	namespaces, err := c.namespaces.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(namespaces))
+	for i, namespace := range namespaces {
+		names[i] = namespace.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (c *Cache) Namespace(name string) (*corev1.Namespace, error) {
+	return c.namespaces.Get(name)
+}
+
+func (c *Cache) Deployments(namespace string) (*v1.DeploymentList, error) {
+	deployments, err := c.deployments.Deployments(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+
+	list := &v1.DeploymentList{Items: make([]v1.Deployment, len(deployments))}
+	for i, deployment := range deployments {
+		list.Items[i] = *deployment.DeepCopy()
+	}
+	return list, nil
+}
+
+func (c *Cache) Deployment(namespace, name string) (*v1.Deployment, error) {
+	deployment, err := c.deployments.Deployments(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return deployment.DeepCopy(), nil
+}
+
+func (c *Cache) CachesConfigMaps(namespace string) bool {
+	return c.configMapNamespace == namespace
+}
+
+func (c *Cache) ConfigMap(name, namespace string) (*corev1.ConfigMap, error) {
+	cm, err := c.configMaps.ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cm.DeepCopy(), nil
+}
+
+func (c *Cache) Downscaler(name string) (*unstructured.Unstructured, error) {
+	object, err := c.downscalers.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	downscaler, ok := object.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected downscaler object %T", object)
+	}
+	return downscaler.DeepCopy(), nil
+}
@@ -0,0 +1,90 @@
+package kas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCachedReads(t *testing.T) {
+	replicas := int32(2)
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "nginx-2"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "nginx-1",
+			Annotations: map[string]string{shared.OverrideAnnotation: shared.OverrideWake, shared.OverrideUntilAnnotation: "2030-01-01T00:00:00Z"},
+		}},
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "nginx-1"}, Spec: v1.DeploymentSpec{Replicas: &replicas}},
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "nginx-1"}, Spec: v1.DeploymentSpec{Replicas: &replicas}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "downscaler-cm", Namespace: shared.DownscalerNamespace}},
+	)
+
+	downscaler := &unstructured.Unstructured{}
+	downscaler.SetAPIVersion(shared.Group + "/" + shared.Version)
+	downscaler.SetKind("Downscaler")
+	downscaler.SetName(shared.DownscalerNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{DownscalerResource: "DownscalerList"},
+		downscaler,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewCache(client, dynamicClient, shared.DownscalerNamespace, 0)
+	if err := cache.Start(ctx); err != nil {
+		t.Fatalf("Start() = %v; expected nil", err)
+	}
+
+	k := &KubernetesImpl{cache: cache}
+
+	tests := []struct {
+		name     string
+		check    func() bool
+		expected string
+	}{
+		{"Namespaces sorted by name", func() bool {
+			namespaces := k.GetNamespaces(ctx)
+			return len(namespaces) == 2 && namespaces[0] == "nginx-1" && namespaces[1] == "nginx-2"
+		}, "[nginx-1 nginx-2]"},
+		{"Deployments sorted by name", func() bool {
+			deployments, err := k.GetDeployments(ctx, "nginx-1")
+			return err == nil && len(deployments.Items) == 2 && deployments.Items[0].Name == "api" && deployments.Items[1].Name == "web"
+		}, "[api web]"},
+		{"Deployments of an unknown namespace", func() bool {
+			deployments, err := k.GetDeployments(ctx, "unknown")
+			return err == nil && len(deployments.Items) == 0
+		}, "no deployments"},
+		{"Namespace overrides", func() bool {
+			overrides := k.GetNamespaceOverrides(ctx, []string{"nginx-1", "nginx-2"})
+			return len(overrides) == 1 && overrides["nginx-1"].Mode == shared.OverrideWake
+		}, "wake override of nginx-1"},
+		{"ConfigMap", func() bool {
+			return k.ListConfigMap(ctx, "downscaler-cm", shared.DownscalerNamespace) != nil
+		}, "downscaler-cm"},
+		{"Missing ConfigMap", func() bool {
+			return k.ListConfigMap(ctx, "missing", shared.DownscalerNamespace) == nil
+		}, "nil"},
+		{"Downscaler", func() bool {
+			obj, err := k.getDownscaler(ctx, DownscalerResource, shared.DownscalerNamespace)
+			return err == nil && obj.GetName() == shared.DownscalerNamespace
+		}, "downscaler object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.check() {
+				t.Errorf("expected %s from the cache", tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package kas
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/status"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ActionScale           = "scale"
+	ActionPatchConfigMap  = "patch"
+	ActionCreateConfigMap = "create"
+	ActionPatchOverride   = "patch override"
+)
+
+type DryRunKubernetes struct {
+	*KubernetesImpl
+	mu      sync.Mutex
+	actions []status.PlannedAction
+	status  *status.Reporter
+}
+
+func NewDryRunKubernetes(k *KubernetesImpl) *DryRunKubernetes {
+	return &DryRunKubernetes{KubernetesImpl: k}
+}
+
+func (d *DryRunKubernetes) AddStatusReporter(reporter *status.Reporter) *DryRunKubernetes {
+	d.status = reporter
+	return d
+}
+
+func (d *DryRunKubernetes) Actions() []status.PlannedAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]status.PlannedAction(nil), d.actions...)
+}
+
+func (d *DryRunKubernetes) ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, desiredReplicas int32, scaledBy string) error {
+	currentReplicas := *deployment.Spec.Replicas
+	slog.Info("dry run", "action", ActionScale, "kind", shared.DeploymentKind, "name", deployment.Name, "namespace", namespace, "current replicas", currentReplicas, "desired replicas", desiredReplicas, "rule", scaledBy)
+
+	d.record(status.PlannedAction{
+		Action:    ActionScale,
+		Kind:      shared.DeploymentKind,
+		Namespace: namespace,
+		Name:      deployment.Name,
+		From:      currentReplicas,
+		To:        desiredReplicas,
+		Rule:      scaledBy,
+	})
+	return nil
+}
+
+func (d *DryRunKubernetes) PatchConfigMap(ctx context.Context, name, namespace string, patch []byte) error {
+	slog.Info("dry run", "action", ActionPatchConfigMap, "kind", "ConfigMap", "name", name, "namespace", namespace)
+	d.record(status.PlannedAction{Action: ActionPatchConfigMap, Kind: "ConfigMap", Namespace: namespace, Name: name})
+	return nil
+}
+
+func (d *DryRunKubernetes) CreateConfigMap(ctx context.Context, name, namespace string) error {
+	slog.Info("dry run", "action", ActionCreateConfigMap, "kind", "ConfigMap", "name", name, "namespace", namespace)
+	d.record(status.PlannedAction{Action: ActionCreateConfigMap, Kind: "ConfigMap", Namespace: namespace, Name: name})
+	return nil
+}
+
+func (d *DryRunKubernetes) PatchNamespaceOverride(ctx context.Context, namespace string, override *shared.Override) error {
+	slog.Info("dry run", "action", ActionPatchOverride, "kind", "Namespace", "name", namespace)
+	d.record(status.PlannedAction{Action: ActionPatchOverride, Kind: "Namespace", Name: namespace})
+	return nil
+}
+
+func (d *DryRunKubernetes) StartUpscaling(ctx context.Context, stateByNamespace map[string]shared.Apps, namespaces []string, spread shared.UpscaleSpread, scaledBy string) map[string]shared.Apps {
+	return startUpscaling(ctx, d, nil, stateByNamespace, namespaces, spread, scaledBy)
+}
+
+func (d *DryRunKubernetes) RetryFailedScaling(ctx context.Context, stateByNamespace map[string]shared.Apps, scaledBy string) map[string]shared.Apps {
+	return retryFailedScaling(ctx, d, stateByNamespace, scaledBy)
+}
+
+func (d *DryRunKubernetes) StartDownscaling(ctx context.Context, namespaces []string, evicted shared.NotUsableNamespacesDuringScheduling, scaledBy string) map[string]shared.Apps {
+	return startDownscaling(ctx, d, nil, namespaces, evicted, scaledBy)
+}
+
+func (d *DryRunKubernetes) record(action status.PlannedAction) {
+	action.Time = metav1.NewTime(time.Now().UTC())
+
+	d.mu.Lock()
+	d.actions = append(d.actions, action)
+	d.mu.Unlock()
+
+	metrics.DryRunAction(action.Action, action.Namespace)
+	d.status.PlannedAction(action)
+}
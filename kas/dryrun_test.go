@@ -0,0 +1,49 @@
+package kas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDryRunScaleDeployments(t *testing.T) {
+	replicas := int32(3)
+	deployment := &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "nginx-2"},
+		Spec:       v1.DeploymentSpec{Replicas: &replicas},
+	}
+
+	tests := []struct {
+		name    string
+		desired int32
+	}{
+		{"Planned downscaling", 0},
+		{"Planned upscaling", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun := NewDryRunKubernetes(&KubernetesImpl{})
+
+			patch, _ := GenerateScalePatch(tt.desired)
+			if err := dryRun.ScaleDeployments(context.Background(), "nginx-2", deployment, patch, tt.desired, "nginx-2 01:30-14:50"); err != nil {
+				t.Fatalf("ScaleDeployments() = %v; expected nil", err)
+			}
+
+			actions := dryRun.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("len(Actions()) = %d; expected 1", len(actions))
+			}
+			action := actions[0]
+			if action.Action != ActionScale || action.Kind != shared.DeploymentKind || action.Name != "nginx" || action.From != 3 || action.To != tt.desired || action.Time.IsZero() {
+				t.Errorf("Actions()[0] = %+v; expected a scale of nginx from 3 to %d", action, tt.desired)
+			}
+			if *deployment.Spec.Replicas != 3 {
+				t.Errorf("deployment replicas = %d; expected the deployment to be untouched", *deployment.Spec.Replicas)
+			}
+		})
+	}
+}
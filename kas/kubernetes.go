@@ -2,38 +2,55 @@ package kas
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/adalbertjnr/downscaler/common"
+	"github.com/adalbertjnr/downscaler/events"
+	"github.com/adalbertjnr/downscaler/metrics"
 	"github.com/adalbertjnr/downscaler/shared"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
+var ErrDownscalerNotFound = errors.New("downscaler not found")
+
 type Kubernetes interface {
 	GetNamespaces(ctx context.Context) []string
-	GetDeployments(ctx context.Context, namespace string) *v1.DeploymentList
-	GetDownscalerData(ctx context.Context, gv schema.GroupVersionResource) (*shared.DownscalerPolicy, error)
-	ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, updateScale int32)
-	GetWatcherByDownscalerCRD(ctx context.Context, name, namespace string) (watch.Interface, error)
-	StartDownscaling(ctx context.Context, namespaces []string, is shared.NotUsableNamespacesDuringScheduling) map[string]shared.Apps
-	StartUpscaling(ctx context.Context, scheduledNamespaces map[string]struct{}, namespaces []string, cmName, cmNamespace string) []map[string]shared.Apps
+	GetDeployments(ctx context.Context, namespace string) (*v1.DeploymentList, error)
+	GetDeployment(ctx context.Context, namespace, name string) (*v1.Deployment, error)
+	GetNamespaceOverrides(ctx context.Context, namespaces []string) map[string]shared.Override
+	PatchNamespaceOverride(ctx context.Context, namespace string, override *shared.Override) error
+	GetDownscalerData(ctx context.Context, gv schema.GroupVersionResource, name string) (*shared.DownscalerPolicy, error)
+	ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, updateScale int32, scaledBy string) error
+	RetryFailedScaling(ctx context.Context, stateByNamespace map[string]shared.Apps, scaledBy string) map[string]shared.Apps
+	GetWatcherByDownscalerCRD(ctx context.Context, resource, name, namespace string) (watch.Interface, error)
+	PatchDownscalerStatus(ctx context.Context, name string, patch []byte) error
+	StartDownscaling(ctx context.Context, namespaces []string, is shared.NotUsableNamespacesDuringScheduling, scaledBy string) map[string]shared.Apps
+	StartUpscaling(ctx context.Context, stateByNamespace map[string]shared.Apps, namespaces []string, spread shared.UpscaleSpread, scaledBy string) map[string]shared.Apps
 	ListConfigMap(ctx context.Context, name, namespace string) *corev1.ConfigMap
-	PatchConfigMap(ctx context.Context, name, namespace string, patch []byte)
+	PatchConfigMap(ctx context.Context, name, namespace string, patch []byte) error
 	CreateConfigMap(ctx context.Context, name, namespace string) error
 }
 
 type KubernetesImpl struct {
 	K8sClient     *kubernetes.Clientset
 	DynamicClient *dynamic.DynamicClient
+	events        *events.Recorder
+	cache         *Cache
 }
 
 func NewKubernetes(client *kubernetes.Clientset, dynamicClient *dynamic.DynamicClient) *KubernetesImpl {
@@ -43,6 +60,16 @@ func NewKubernetes(client *kubernetes.Clientset, dynamicClient *dynamic.DynamicC
 	}
 }
 
+func (k *KubernetesImpl) AddEventRecorder(recorder *events.Recorder) *KubernetesImpl {
+	k.events = recorder
+	return k
+}
+
+func (k *KubernetesImpl) AddCache(cache *Cache) *KubernetesImpl {
+	k.cache = cache
+	return k
+}
+
 func (k KubernetesImpl) CreateConfigMap(ctx context.Context, name, namespace string) error {
 	create := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -55,6 +82,7 @@ func (k KubernetesImpl) CreateConfigMap(ctx context.Context, name, namespace str
 	_, err := k.K8sClient.CoreV1().ConfigMaps(namespace).Create(ctx, create, metav1.CreateOptions{})
 	if err != nil {
 		slog.Error("configmap", "name", name, "namespace", namespace, "verb", "create", "err", err)
+		metrics.APIError("configmaps", "create")
 		return err
 	}
 
@@ -63,12 +91,22 @@ func (k KubernetesImpl) CreateConfigMap(ctx context.Context, name, namespace str
 }
 
 func (k KubernetesImpl) ListConfigMap(ctx context.Context, name, namespace string) *corev1.ConfigMap {
+	if k.cache.Synced() && k.cache.CachesConfigMaps(namespace) {
+		cm, err := k.cache.ConfigMap(name, namespace)
+		if err != nil {
+			slog.Error("configmap", "name", name, "namespace", namespace, "verb", "get", "source", "cache", "err", err)
+		}
+		return cm
+	}
+
 	cm, err := k.K8sClient.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{
 		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
 	},
 	)
 	if err != nil {
 		slog.Error("configmap", "name", name, "namespace", namespace, "verb", "list", "err", err)
+		metrics.APIError("configmaps", "list")
+		return nil
 	}
 	if len(cm.Items) > 0 {
 		return &cm.Items[0]
@@ -77,24 +115,26 @@ func (k KubernetesImpl) ListConfigMap(ctx context.Context, name, namespace strin
 	return nil
 }
 
-func (k KubernetesImpl) PatchConfigMap(ctx context.Context, name, namespace string, patch []byte) {
-	_, err := k.K8sClient.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+func (k KubernetesImpl) PatchConfigMap(ctx context.Context, name, namespace string, patch []byte) error {
+	err := withRetry(func() error {
+		_, err := k.K8sClient.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
 	if err != nil {
 		slog.Error("configmap", "name", name, "namespace", namespace, "verb", "patch", "err", err)
+		metrics.APIError("configmaps", "patch")
+		return err
 	}
+	return nil
 }
 
-func (k KubernetesImpl) GetDownscalerData(ctx context.Context, gv schema.GroupVersionResource) (*shared.DownscalerPolicy, error) {
-	list, err := k.DynamicClient.Resource(gv).Namespace("").List(ctx, metav1.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("metadata.name", "downscaler").String(),
-	})
+func (k KubernetesImpl) GetDownscalerData(ctx context.Context, gv schema.GroupVersionResource, name string) (*shared.DownscalerPolicy, error) {
+	obj, err := k.getDownscaler(ctx, gv, name)
 	if err != nil {
-		slog.Error("crd", "kind", "downscaler", "verb", "list", "err", err)
 		return nil, err
 	}
 
 	data := &shared.DownscalerPolicy{}
-	obj := &list.Items[0]
 	if err := common.UnmarshalDataPolicy(obj, data); err != nil {
 		slog.Error("unmarshaling", "error", err)
 		return nil, err
@@ -103,10 +143,47 @@ func (k KubernetesImpl) GetDownscalerData(ctx context.Context, gv schema.GroupVe
 	return data, nil
 }
 
+func (k KubernetesImpl) getDownscaler(ctx context.Context, gv schema.GroupVersionResource, name string) (*unstructured.Unstructured, error) {
+	if k.cache.Synced() && gv == DownscalerResource {
+		obj, err := k.cache.Downscaler(name)
+		if apierrors.IsNotFound(err) {
+			return nil, ErrDownscalerNotFound
+		}
+		if err != nil {
+			slog.Error("crd", "kind", "downscaler", "name", name, "verb", "get", "source", "cache", "err", err)
+			return nil, err
+		}
+		return obj, nil
+	}
+
+	list, err := k.DynamicClient.Resource(gv).Namespace("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		slog.Error("crd", "kind", "downscaler", "verb", "list", "err", err)
+		metrics.APIError("downscalers", "list")
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, ErrDownscalerNotFound
+	}
+	return &list.Items[0], nil
+}
+
 func (k KubernetesImpl) GetNamespaces(ctx context.Context) []string {
+	if k.cache.Synced() {
+		namespaces, err := k.cache.Namespaces()
+		if err != nil {
+			slog.Error("namespace", "verb", "list", "source", "cache", "error", err)
+			return nil
+		}
+		return namespaces
+	}
+
 	namespaces, err := k.K8sClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		slog.Error("namespace", "verb", "list", "error", err)
+		metrics.APIError("namespaces", "list")
 		return nil
 	}
 
@@ -118,85 +195,177 @@ func (k KubernetesImpl) GetNamespaces(ctx context.Context) []string {
 	return namespacesNames
 }
 
-func (k KubernetesImpl) GetDeployments(ctx context.Context, namespace string) *v1.DeploymentList {
+func (k KubernetesImpl) GetNamespaceOverrides(ctx context.Context, namespaces []string) map[string]shared.Override {
+	if k.cache.Synced() {
+		overrides := make(map[string]shared.Override)
+		for _, name := range namespaces {
+			namespace, err := k.cache.Namespace(name)
+			if err != nil {
+				continue
+			}
+			if override, found := shared.ParseOverride(namespace.Annotations); found {
+				overrides[name] = override
+			}
+		}
+		return overrides
+	}
+
+	namespaceList, err := k.K8sClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Error("namespace", "verb", "list", "error", err)
+		metrics.APIError("namespaces", "list")
+		return nil
+	}
+
+	wanted := make(map[string]struct{}, len(namespaces))
+	for _, namespace := range namespaces {
+		wanted[namespace] = struct{}{}
+	}
+
+	overrides := make(map[string]shared.Override)
+	for _, namespace := range namespaceList.Items {
+		if _, found := wanted[namespace.Name]; !found {
+			continue
+		}
+		if override, found := shared.ParseOverride(namespace.Annotations); found {
+			overrides[namespace.Name] = override
+		}
+	}
+	return overrides
+}
+
+func (k KubernetesImpl) PatchNamespaceOverride(ctx context.Context, namespace string, override *shared.Override) error {
+	annotations := map[string]interface{}{
+		shared.OverrideAnnotation:      nil,
+		shared.OverrideUntilAnnotation: nil,
+	}
+	if override != nil {
+		annotations[shared.OverrideAnnotation] = override.Mode
+		annotations[shared.OverrideUntilAnnotation] = override.Until.UTC().Format(time.RFC3339)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+
+	err = withRetry(func() error {
+		_, err := k.K8sClient.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		slog.Error("namespace", "name", namespace, "verb", "patch", "err", err)
+		metrics.APIError("namespaces", "patch")
+		return err
+	}
+	return nil
+}
+
+func (k KubernetesImpl) GetDeployments(ctx context.Context, namespace string) (*v1.DeploymentList, error) {
+	if k.cache.Synced() {
+		deployments, err := k.cache.Deployments(namespace)
+		if err != nil {
+			slog.Error("deployments", "verb", "list", "namespace", namespace, "source", "cache", "err", err)
+			return nil, err
+		}
+		return deployments, nil
+	}
+
 	deployments, err := k.K8sClient.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		slog.Error("deployments", "verb", "list", "namespace", namespace, "err", err)
-		return nil
+		metrics.APIError("deployments", "list")
+		return nil, err
 	}
 
-	return deployments
+	return deployments, nil
 }
 
-func (k KubernetesImpl) ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, desiredReplicas int32) {
+func (k KubernetesImpl) GetDeployment(ctx context.Context, namespace, name string) (*v1.Deployment, error) {
+	if k.cache.Synced() {
+		return k.cache.Deployment(namespace, name)
+	}
+
+	deployment, err := k.K8sClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		slog.Error("deployments", "name", name, "namespace", namespace, "verb", "get", "err", err)
+		metrics.APIError("deployments", "get")
+	}
+	return deployment, err
+}
+
+func (k KubernetesImpl) ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, desiredReplicas int32, scaledBy string) error {
 	currentReplicas := *deployment.Spec.Replicas
 
-	_, err := k.K8sClient.AppsV1().Deployments(namespace).Patch(ctx, deployment.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	err := withRetry(func() error {
+		_, err := k.K8sClient.AppsV1().Deployments(namespace).Patch(ctx, deployment.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
 	if err != nil {
 		slog.Error("deployments", "name", deployment.Name, "namespace", namespace, "current replicas", currentReplicas, "desired replicas", desiredReplicas, "verb", "update", "err", err)
-		return
+		metrics.APIError("deployments", "patch")
+		metrics.ScaleOperation(namespace, currentReplicas, desiredReplicas, err)
+		k.events.ScaleFailed(deployment, currentReplicas, desiredReplicas, scaledBy, err)
+		return err
 	}
 
+	metrics.ScaleOperation(namespace, currentReplicas, desiredReplicas, nil)
+	if currentReplicas != desiredReplicas {
+		k.events.Scaled(deployment, currentReplicas, desiredReplicas, scaledBy)
+	}
 	slog.Info("deployments", "name", deployment.Name, "namespace", namespace, "current replicas", currentReplicas, "desired replicas", desiredReplicas, "verb", "update", "err", err)
+	return nil
 }
 
-func (k KubernetesImpl) GetWatcherByDownscalerCRD(ctx context.Context, name, namespace string) (watch.Interface, error) {
+func (k KubernetesImpl) GetWatcherByDownscalerCRD(ctx context.Context, resource, name, namespace string) (watch.Interface, error) {
 	timeout := int64(3600)
-	watcher, err := k.DynamicClient.Resource(schema.GroupVersionResource{
-		Group:    shared.Group,
-		Version:  shared.Version,
-		Resource: shared.Resource,
-	}).Namespace("").Watch(ctx, metav1.ListOptions{
-		FieldSelector:  fields.OneTermEqualSelector("metadata.name", name).String(),
-		TimeoutSeconds: &timeout,
-	})
+	options := metav1.ListOptions{TimeoutSeconds: &timeout}
+	if name != "" {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+
+	gvr := schema.GroupVersionResource{Group: shared.Group, Version: shared.Version, Resource: resource}
+	watcher, err := k.DynamicClient.Resource(gvr).Namespace(namespace).Watch(ctx, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create the watcher. downscaler name %s. err: %v", name, err)
+		metrics.APIError(resource, "watch")
+		return nil, fmt.Errorf("failed to create the watcher. %s name %s. err: %v", resource, name, err)
 	}
-	slog.Info("watcher", "group", shared.Group, "version", shared.Version, "resource", shared.Resource, "verb", "watch", "status", "created")
+	slog.Info("watcher", "group", shared.Group, "version", shared.Version, "resource", resource, "verb", "watch", "status", "created")
 	return watcher, nil
 }
 
-func (k KubernetesImpl) StartUpscaling(ctx context.Context, scheduledNamespaces map[string]struct{}, namespaces []string, cmName, cmNamespace string) []map[string]shared.Apps {
-	cm := k.ListConfigMap(ctx, cmName, cmNamespace)
-	sliceToWrite := make([]map[string]shared.Apps, len(namespaces))
-
-	apps := make(map[string]shared.Apps)
-	if err := common.UnmarshalDataPolicy(cm, apps); err != nil {
-		slog.Error("unmarshal cm apps", "err", err)
+func (k KubernetesImpl) PatchDownscalerStatus(ctx context.Context, key string, patch []byte) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
 	}
 
-	deploymentMapList := filterDeploymentsByNamespace(ctx, namespaces, k)
-	extractedStateByNamespaces := extractIndexByNamespaces(apps, namespaces)
-
-	for _, namespace := range namespaces {
-		if cmValue, found := extractedStateByNamespaces[namespace+".yaml"]; found {
-			indexToWrite := runUpscalingByDeploymentNameStateIndex(ctx, k,
-				namespace,
-				cmValue,
-				deploymentMapList,
-			)
-			sliceToWrite = append(sliceToWrite, indexToWrite)
-		}
+	gvr := DownscalerResource
+	if namespace != "" {
+		gvr = NamespaceDownscalerResource
 	}
 
-	return sliceToWrite
+	err = withRetry(func() error {
+		_, err := k.DynamicClient.Resource(gvr).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+		return err
+	})
+	if err != nil {
+		slog.Error("crd", "kind", "downscaler", "name", key, "verb", "patch status", "err", err)
+		metrics.APIError(gvr.Resource+"/status", "patch")
+		return err
+	}
+	return nil
 }
 
-func (k KubernetesImpl) StartDownscaling(ctx context.Context, namespaces []string, evicted shared.NotUsableNamespacesDuringScheduling,
-) map[string]shared.Apps {
-	deploymentStateByNamespace := make(map[string]shared.Apps)
-	for _, namespace := range namespaces {
-		if isNamespaceIgnored(namespace, evicted) {
-			continue
-		}
-		deploymentAndReplicasFingerprint, _ := downscaleNamespace(ctx, k, namespace, shared.DefaultGroup)
-		deploymentStateByNamespace[namespace] = deploymentAndReplicasFingerprint
-	}
+func (k KubernetesImpl) StartUpscaling(ctx context.Context, stateByNamespace map[string]shared.Apps, namespaces []string, spread shared.UpscaleSpread, scaledBy string) map[string]shared.Apps {
+	return startUpscaling(ctx, k, k.events, stateByNamespace, namespaces, spread, scaledBy)
+}
 
-	if isDownscalerPresent(namespaces) {
-		downscaleTheDownscaler(ctx, k, evicted)
-	}
+func (k KubernetesImpl) RetryFailedScaling(ctx context.Context, stateByNamespace map[string]shared.Apps, scaledBy string) map[string]shared.Apps {
+	return retryFailedScaling(ctx, k, stateByNamespace, scaledBy)
+}
 
-	return deploymentStateByNamespace
+func (k KubernetesImpl) StartDownscaling(ctx context.Context, namespaces []string, evicted shared.NotUsableNamespacesDuringScheduling, scaledBy string,
+) map[string]shared.Apps {
+	return startDownscaling(ctx, k, k.events, namespaces, evicted, scaledBy)
 }
@@ -0,0 +1,91 @@
+package kas
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+var patchBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+func isTransientError(err error) bool {
+	return apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsConflict(err)
+}
+
+func withRetry(fn func() error) error {
+	return retry.OnError(patchBackoff, isTransientError, fn)
+}
+
+// a failed scaling is recorded in the state with its previous phase and the error, so
+// only the namespaces with a failed workload are returned to be written back
+func retryFailedScaling(ctx context.Context, k Kubernetes, stateByNamespace map[string]shared.Apps, scaledBy string) map[string]shared.Apps {
+	stateToWrite := make(map[string]shared.Apps)
+	for namespace, apps := range stateByNamespace {
+		retried := apps
+		retried.State = make([]shared.Workload, 0, len(apps.State))
+
+		changed := false
+		for _, workload := range apps.State {
+			if !workload.Failed() {
+				retried.State = append(retried.State, workload)
+				continue
+			}
+			changed = true
+			if workload, keep := retryWorkload(ctx, k, namespace, workload, scaledBy); keep {
+				retried.State = append(retried.State, workload)
+			}
+		}
+		if changed {
+			stateToWrite[namespace] = retried
+		}
+	}
+	return stateToWrite
+}
+
+func retryWorkload(ctx context.Context, k Kubernetes, namespace string, workload shared.Workload, scaledBy string) (shared.Workload, bool) {
+	deployment, err := k.GetDeployment(ctx, namespace, workload.Name)
+	if apierrors.IsNotFound(err) || (err == nil && !workload.Matches(deployment.Name, string(deployment.UID))) {
+		slog.Warn("deployments", "name", workload.Name, "namespace", namespace, "verb", "retry", "status", "dropped", "reason", "not found")
+		return workload, false
+	}
+	if err != nil {
+		return workload, true
+	}
+
+	replicas := workload.TargetReplicas()
+	patch, err := GenerateScalePatch(replicas)
+	if err != nil {
+		slog.Error("patch marshaling error", "err", err)
+		return workload, true
+	}
+
+	slog.Info("deployments", "name", workload.Name, "namespace", namespace, "phase", workload.Phase, "desired replicas", replicas, "last error", workload.LastError, "verb", "retry")
+	if err := k.ScaleDeployments(ctx, namespace, deployment, patch, replicas, scaledBy); err != nil {
+		workload.LastError = err.Error()
+		return workload, true
+	}
+
+	workload.Phase = workload.TargetPhase()
+	workload.ScaledAt = time.Now().UTC()
+	workload.ScaledBy = scaledBy
+	workload.LastError = ""
+	if workload.Phase == shared.PhaseDownscaled {
+		workload.TemplateHash = TemplateHash(deployment)
+	}
+	return workload, true
+}
@@ -3,84 +3,212 @@ package kas
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/adalbertjnr/downscaler/events"
 	"github.com/adalbertjnr/downscaler/shared"
 	v1 "k8s.io/api/apps/v1"
 )
 
-func runUpscalingByDeploymentNameStateIndex(ctx context.Context, k KubernetesImpl, namespace string, cmValue shared.Apps, deploymentMapList map[string]*v1.Deployment) map[string]shared.Apps {
-	var newState []string
-	for _, cmStoredState := range cmValue.State {
-		cmDeploymentName, cmDeploymentReplicas := getMetadataReplicas(cmStoredState)
+type upscaleSchedule struct {
+	start   time.Time
+	offsets []time.Duration
+	next    int
+}
 
-		patch, err := generateScalePatch(cmDeploymentReplicas)
-		if err != nil {
-			slog.Error("generating patch error", "err", err)
+func startUpscaling(ctx context.Context, k Kubernetes, recorder *events.Recorder, stateByNamespace map[string]shared.Apps, namespaces []string, spread shared.UpscaleSpread, scaledBy string) map[string]shared.Apps {
+	stateToWrite := make(map[string]shared.Apps, len(stateByNamespace))
+
+	deploymentMapList, unlisted := filterDeploymentsByNamespace(ctx, namespaces, k)
+
+	schedule := newUpscaleSchedule(spread, countUpscalableWorkloads(stateByNamespace, deploymentMapList))
+	if spread.Enabled() {
+		slog.Info("upscaling", "namespace(s)", namespaces, "spread", spread.Duration.String(), "mode", spread.Mode, "workloads", len(schedule.offsets))
+	}
+
+	for _, namespace := range namespaces {
+		if _, failed := unlisted[namespace]; failed {
+			slog.Warn("upscaling", "namespace", namespace, "status", "deployments not listed", "next retry", "next cycle")
 			continue
 		}
+		if stateValue, found := stateByNamespace[namespace]; found {
+			upscaledState, upscaled := runUpscalingByDeploymentNameStateIndex(ctx, k,
+				namespace,
+				stateValue,
+				deploymentMapList,
+				schedule,
+				scaledBy,
+			)
+			stateToWrite[namespace] = upscaledState
+			recorder.NamespaceScaled(namespace, events.ReasonUpscaled, upscaled, scaledBy)
+		}
+	}
 
-		deployment := deploymentMapList[cmDeploymentName]
-		if deployment != nil {
-			k.ScaleDeployments(ctx, namespace, deployment, patch, cmDeploymentReplicas)
+	return stateToWrite
+}
 
-			stateAfterUpscaling := createNewStateIndex(cmStoredState)
-			newState = append(newState, stateAfterUpscaling)
+func startDownscaling(ctx context.Context, k Kubernetes, recorder *events.Recorder, namespaces []string, evicted shared.NotUsableNamespacesDuringScheduling, scaledBy string) map[string]shared.Apps {
+	deploymentStateByNamespace := make(map[string]shared.Apps)
+	for _, namespace := range namespaces {
+		if isNamespaceIgnored(namespace, evicted) {
+			continue
+		}
+		deploymentAndReplicasFingerprint, err := downscaleNamespace(ctx, k, namespace, shared.DefaultGroup, scaledBy)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("downscaling", "namespace", namespace, "status", "deployments not listed", "next retry", "next cycle")
+			continue
+		}
+		if err != nil && len(deploymentAndReplicasFingerprint.State) == 0 {
+			break
+		}
+		deploymentStateByNamespace[namespace] = deploymentAndReplicasFingerprint
+		downscaled := 0
+		for _, workload := range deploymentAndReplicasFingerprint.State {
+			if workload.Phase == shared.PhaseDownscaled {
+				downscaled++
+			}
+		}
+		recorder.NamespaceScaled(namespace, events.ReasonDownscaled, downscaled, scaledBy)
+		if err != nil {
+			break
 		}
 	}
-	return map[string]shared.Apps{
-		namespace: {
-			Status: cmValue.Status,
-			Group:  cmValue.Group,
-			State:  newState,
-		},
+
+	if isDownscalerPresent(namespaces) && ctx.Err() == nil {
+		downscaleTheDownscaler(ctx, k, evicted, scaledBy)
 	}
+
+	return deploymentStateByNamespace
 }
 
-func createNewStateIndex(previousState string) string {
-	previousStateParts := strings.Split(previousState, ",")
-	return fmt.Sprintf("%s,%s,%d", previousStateParts[0], previousStateParts[1], shared.DeploymentsWithUpscaledState)
+func newUpscaleSchedule(spread shared.UpscaleSpread, workloads int) *upscaleSchedule {
+	return &upscaleSchedule{
+		start:   time.Now(),
+		offsets: spread.Offsets(workloads),
+	}
 }
 
-func extractIndexByNamespaces(cmCurrentState map[string]shared.Apps, namespaces []string) map[string]shared.Apps {
-	nsIndex := make(map[string]shared.Apps, len(namespaces))
-	for _, namespace := range namespaces {
-		if value, found := cmCurrentState[namespace+".yaml"]; found {
-			nsIndex[namespace+".yaml"] = value
+func (s *upscaleSchedule) wait(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if s.next >= len(s.offsets) {
+		return true
+	}
+	offset := s.offsets[s.next]
+	s.next++
+
+	delay := time.Until(s.start.Add(offset))
+	if delay <= 0 {
+		return true
+	}
+
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func countUpscalableWorkloads(stateByNamespaces map[string]shared.Apps, deploymentMapList map[string]*v1.Deployment) int {
+	workloads := 0
+	for namespace, cmValue := range stateByNamespaces {
+		for _, workload := range cmValue.State {
+			if workload.Phase == shared.PhaseDownscaled && liveWorkload(deploymentMapList, namespace, workload) != nil {
+				workloads++
+			}
 		}
 	}
+	return workloads
+}
 
-	return nsIndex
+func liveWorkload(deploymentMapList map[string]*v1.Deployment, namespace string, workload shared.Workload) *v1.Deployment {
+	deployment := deploymentMapList[shared.WorkloadKey(namespace, workload.Name)]
+	if deployment == nil || !workload.Matches(deployment.Name, string(deployment.UID)) {
+		return nil
+	}
+	return deployment
 }
 
-func filterDeploymentsByNamespace(ctx context.Context, namespaces []string, k KubernetesImpl) map[string]*v1.Deployment {
-	deploymentListMap := make(map[string]*v1.Deployment)
-	for _, namespace := range namespaces {
-		deploymentList := k.GetDeployments(ctx, namespace)
+func runUpscalingByDeploymentNameStateIndex(ctx context.Context, k Kubernetes, namespace string, cmValue shared.Apps, deploymentMapList map[string]*v1.Deployment, schedule *upscaleSchedule, scaledBy string) (shared.Apps, int) {
+	var (
+		newState []shared.Workload
+		upscaled int
+	)
+	for i, workload := range cmValue.State {
+		if workload.Phase != shared.PhaseDownscaled {
+			// a downscaling that failed is not retried once the namespace is upscaled
+			workload.LastError = ""
+			newState = append(newState, workload)
+			continue
+		}
 
-		for _, deployment := range deploymentList.Items {
-			deploymentListMap[deployment.Name] = &deployment
+		patch, err := GenerateScalePatch(workload.OriginalReplicas)
+		if err != nil {
+			slog.Error("generating patch error", "err", err)
+			continue
 		}
-	}
 
-	return deploymentListMap
+		deployment := liveWorkload(deploymentMapList, namespace, workload)
+		if deployment == nil {
+			status := "deleted while downscaled"
+			if recreated := deploymentMapList[shared.WorkloadKey(namespace, workload.Name)]; recreated != nil {
+				status = "recreated while downscaled"
+			}
+			slog.Info("upscaling", "name", workload.Name, "namespace", namespace, "uid", workload.UID, "status", status, "action", "pruned from the state")
+			continue
+		}
+
+		if !schedule.wait(ctx) {
+			slog.Warn("upscaling", "namespace", namespace, "status", "interrupted", "upscaled", upscaled)
+			newState = append(newState, cmValue.State[i:]...)
+			break
+		}
+		if err := k.ScaleDeployments(ctx, namespace, deployment, patch, workload.OriginalReplicas, scaledBy); err != nil {
+			slog.Warn("upscaling", "name", deployment.Name, "namespace", namespace, "status", "failed", "phase", workload.Phase, "next retry", "next cycle")
+			workload.LastError = err.Error()
+			newState = append(newState, workload)
+			continue
+		}
+		upscaled++
+
+		workload.Phase = shared.PhaseUpscaled
+		workload.ScaledAt = time.Now().UTC()
+		workload.ScaledBy = scaledBy
+		workload.LastError = ""
+		newState = append(newState, workload)
+	}
+	return shared.Apps{
+		SchemaVersion: shared.StateSchemaVersion,
+		Status:        cmValue.Status,
+		Group:         cmValue.Group,
+		State:         newState,
+	}, upscaled
 }
 
-func getMetadataReplicas(state string) (name string, replicas int32) {
-	parts := strings.Split(state, ",")
-	replicasInt, err := strconv.Atoi(parts[1])
-	if err != nil {
-		slog.Error("get replicas conversion error", "err", err)
+func filterDeploymentsByNamespace(ctx context.Context, namespaces []string, k Kubernetes) (map[string]*v1.Deployment, map[string]struct{}) {
+	deploymentListMap := make(map[string]*v1.Deployment)
+	unlisted := make(map[string]struct{})
+	for _, namespace := range namespaces {
+		deploymentList, err := k.GetDeployments(ctx, namespace)
+		if err != nil {
+			unlisted[namespace] = struct{}{}
+			continue
+		}
+
+		for _, deployment := range deploymentList.Items {
+			deploymentListMap[shared.WorkloadKey(namespace, deployment.Name)] = &deployment
+		}
 	}
-	deploymentName := parts[0]
 
-	return deploymentName, int32(replicasInt)
+	return deploymentListMap, unlisted
 }
 
-func generateScalePatch(updateScale int32) ([]byte, error) {
+func GenerateScalePatch(updateScale int32) ([]byte, error) {
 	patch := struct {
 		Spec struct {
 			Replicas *int32 `json:"replicas"`
@@ -108,20 +236,46 @@ func isNamespaceIgnored(namespace string, evicted shared.NotUsableNamespacesDuri
 	return false
 }
 
-func downscaleNamespace(ctx context.Context, k Kubernetes, namespace, group string) (shared.Apps, error) {
-	deploymentsWithinNamespace := k.GetDeployments(ctx, namespace)
+func downscaleNamespace(ctx context.Context, k Kubernetes, namespace, group, scaledBy string) (shared.Apps, error) {
+	deploymentsWithinNamespace, err := k.GetDeployments(ctx, namespace)
+	if err != nil {
+		return shared.Apps{}, err
+	}
 
-	deploymentAndReplicas := make([]string, len(deploymentsWithinNamespace.Items))
+	deploymentAndReplicas := make([]shared.Workload, len(deploymentsWithinNamespace.Items))
 	for i, deployment := range deploymentsWithinNamespace.Items {
-		deploymentAndReplicas[i] = fmt.Sprintf("%s,%d,%d", deployment.Name, *deployment.Spec.Replicas, shared.DeploymentsWithDownscaledState)
+		if ctx.Err() != nil {
+			slog.Warn("downscaling", "namespace", namespace, "status", "interrupted", "downscaled", i, "pending", len(deploymentAndReplicas)-i)
+			return shared.Apps{
+				SchemaVersion: shared.StateSchemaVersion,
+				Status:        shared.NotEmptyNamespace,
+				Group:         group,
+				State:         deploymentAndReplicas[:i],
+			}, ctx.Err()
+		}
+
+		deploymentAndReplicas[i] = shared.Workload{
+			Kind:             shared.DeploymentKind,
+			Name:             deployment.Name,
+			UID:              string(deployment.UID),
+			OriginalReplicas: *deployment.Spec.Replicas,
+			ScaledAt:         time.Now().UTC(),
+			ScaledBy:         scaledBy,
+			TemplateHash:     TemplateHash(&deployment),
+			Phase:            shared.PhaseDownscaled,
+		}
 
 		updateScale := int32(0)
-		patchBytes, err := generateScalePatch(updateScale)
+		patchBytes, err := GenerateScalePatch(updateScale)
 		if err != nil {
 			slog.Error("patch marshaling error", "err", err)
 		}
 
-		k.ScaleDeployments(ctx, namespace, &deployment, patchBytes, updateScale)
+		if err := k.ScaleDeployments(ctx, namespace, &deployment, patchBytes, updateScale, scaledBy); err != nil {
+			slog.Warn("downscaling", "name", deployment.Name, "namespace", namespace, "status", "failed", "phase", shared.PhaseUpscaled, "next retry", "next cycle")
+			deploymentAndReplicas[i].Phase = shared.PhaseUpscaled
+			deploymentAndReplicas[i].LastError = err.Error()
+		}
 	}
 
 	status := shared.NotEmptyNamespace
@@ -130,9 +284,10 @@ func downscaleNamespace(ctx context.Context, k Kubernetes, namespace, group stri
 	}
 
 	return shared.Apps{
-		Status: status,
-		Group:  group,
-		State:  deploymentAndReplicas,
+		SchemaVersion: shared.StateSchemaVersion,
+		Status:        status,
+		Group:         group,
+		State:         deploymentAndReplicas,
 	}, nil
 }
 
@@ -145,18 +300,33 @@ func isDownscalerPresent(namespaces []string) bool {
 	return false
 }
 
-func downscaleTheDownscaler(ctx context.Context, k Kubernetes, evicted shared.NotUsableNamespacesDuringScheduling) {
+func downscaleTheDownscaler(ctx context.Context, k Kubernetes, evicted shared.NotUsableNamespacesDuringScheduling, scaledBy string) {
 	if _, found := evicted.IgnoredNamespaces[shared.DownscalerNamespace]; !found {
-		deployments := k.GetDeployments(ctx, shared.DownscalerNamespace)
+		deployments, err := k.GetDeployments(ctx, shared.DownscalerNamespace)
+		if err != nil {
+			return
+		}
 
 		scaleUpdate := int32(0)
-		patchBytes, err := generateScalePatch(scaleUpdate)
+		patchBytes, err := GenerateScalePatch(scaleUpdate)
 		if err != nil {
 			slog.Error("patch marshaling error", "err", err)
 		}
 
 		for _, deployment := range deployments.Items {
-			k.ScaleDeployments(ctx, shared.DownscalerNamespace, &deployment, patchBytes, scaleUpdate)
+			if err := k.ScaleDeployments(ctx, shared.DownscalerNamespace, &deployment, patchBytes, scaleUpdate, scaledBy); err != nil {
+				slog.Warn("downscaling", "name", deployment.Name, "namespace", shared.DownscalerNamespace, "status", "failed")
+			}
 		}
 	}
 }
+
+func TemplateHash(deployment *v1.Deployment) string {
+	template, err := json.Marshal(deployment.Spec.Template)
+	if err != nil {
+		return ""
+	}
+	hash := fnv.New32a()
+	hash.Write(template)
+	return strconv.FormatUint(uint64(hash.Sum32()), 16)
+}
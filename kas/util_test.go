@@ -0,0 +1,133 @@
+package kas
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScalingStopsWhenAborted(t *testing.T) {
+	replicas := int32(2)
+	client := fake.NewSimpleClientset(
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "nginx-1"}, Spec: v1.DeploymentSpec{Replicas: &replicas}},
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "nginx-1"}, Spec: v1.DeploymentSpec{Replicas: &replicas}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{DownscalerResource: "DownscalerList"},
+	)
+
+	cache := NewCache(client, dynamicClient, shared.DownscalerNamespace, 0)
+	if err := cache.Start(ctx); err != nil {
+		t.Fatalf("Start() = %v; expected nil", err)
+	}
+
+	downscaled := shared.Apps{Status: shared.NotEmptyNamespace, State: []shared.Workload{
+		{Kind: shared.DeploymentKind, Name: "api", OriginalReplicas: 2, Phase: shared.PhaseDownscaled},
+		{Kind: shared.DeploymentKind, Name: "web", OriginalReplicas: 2, Phase: shared.PhaseDownscaled},
+	}}
+
+	tests := []struct {
+		name    string
+		aborted bool
+		scale   func(ctx context.Context, k *DryRunKubernetes) map[string]shared.Apps
+		check   func(state map[string]shared.Apps) bool
+	}{
+		{"Downscaling", false, func(ctx context.Context, k *DryRunKubernetes) map[string]shared.Apps {
+			return k.StartDownscaling(ctx, []string{"nginx-1"}, shared.NotUsableNamespacesDuringScheduling{}, "nginx-1 01:30-14:50")
+		}, func(state map[string]shared.Apps) bool {
+			return len(state["nginx-1"].State) == 2
+		}},
+		{"Aborted downscaling keeps no state for the untouched namespace", true, func(ctx context.Context, k *DryRunKubernetes) map[string]shared.Apps {
+			return k.StartDownscaling(ctx, []string{"nginx-1"}, shared.NotUsableNamespacesDuringScheduling{}, "nginx-1 01:30-14:50")
+		}, func(state map[string]shared.Apps) bool {
+			_, found := state["nginx-1"]
+			return !found
+		}},
+		{"Aborted upscaling keeps the workloads downscaled", true, func(ctx context.Context, k *DryRunKubernetes) map[string]shared.Apps {
+			return k.StartUpscaling(ctx, map[string]shared.Apps{"nginx-1": downscaled}, []string{"nginx-1"}, shared.UpscaleSpread{}, "nginx-1 01:30-14:50")
+		}, func(state map[string]shared.Apps) bool {
+			workloads := state["nginx-1"].State
+			return len(workloads) == 2 && workloads[0].Phase == shared.PhaseDownscaled && workloads[1].Phase == shared.PhaseDownscaled
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun := NewDryRunKubernetes(&KubernetesImpl{cache: cache})
+
+			scaleCtx, abort := context.WithCancel(context.Background())
+			if tt.aborted {
+				abort()
+			}
+			defer abort()
+
+			if state := tt.scale(scaleCtx, dryRun); !tt.check(state) {
+				t.Errorf("unexpected state %+v", state)
+			}
+			if tt.aborted && len(dryRun.Actions()) != 0 {
+				t.Errorf("len(Actions()) = %d; expected no scaling after the abort", len(dryRun.Actions()))
+			}
+		})
+	}
+}
+
+type failingKubernetes struct {
+	*DryRunKubernetes
+	err error
+}
+
+func (f *failingKubernetes) ScaleDeployments(ctx context.Context, namespace string, deployment *v1.Deployment, patch []byte, desiredReplicas int32, scaledBy string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.DryRunKubernetes.ScaleDeployments(ctx, namespace, deployment, patch, desiredReplicas, scaledBy)
+}
+
+func TestFailedDownscalingKeepsThePreviousPhase(t *testing.T) {
+	replicas := int32(2)
+	client := fake.NewSimpleClientset(
+		&v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "nginx-1"}, Spec: v1.DeploymentSpec{Replicas: &replicas}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{DownscalerResource: "DownscalerList"},
+	)
+
+	cache := NewCache(client, dynamicClient, shared.DownscalerNamespace, 0)
+	if err := cache.Start(ctx); err != nil {
+		t.Fatalf("Start() = %v; expected nil", err)
+	}
+
+	k := &failingKubernetes{DryRunKubernetes: NewDryRunKubernetes(&KubernetesImpl{cache: cache}), err: errors.New("etcdserver: request timed out")}
+
+	downscaled := startDownscaling(ctx, k, nil, []string{"nginx-1"}, shared.NotUsableNamespacesDuringScheduling{}, "nginx-1 01:30-14:50")
+	workloads := downscaled["nginx-1"].State
+	if len(workloads) != 1 || workloads[0].Phase != shared.PhaseUpscaled || !workloads[0].Failed() {
+		t.Fatalf("state after a failed downscaling = %+v; expected api upscaled with the error", workloads)
+	}
+
+	k.err = nil
+	upscaled := startUpscaling(ctx, k, nil, downscaled, []string{"nginx-1"}, shared.UpscaleSpread{}, "nginx-1 01:30-14:50")
+	workloads = upscaled["nginx-1"].State
+	if len(workloads) != 1 || workloads[0].Phase != shared.PhaseUpscaled || workloads[0].Failed() {
+		t.Errorf("state after the upscaling = %+v; expected api upscaled without the pending retry", workloads)
+	}
+	if len(k.Actions()) != 0 {
+		t.Errorf("len(Actions()) = %d; expected no scaling of the already upscaled api", len(k.Actions()))
+	}
+}
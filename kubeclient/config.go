@@ -1,33 +1,67 @@
 package kubeclient
 
 import (
+	"log/slog"
+	"os"
+
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
-func NewClientOrDie() (*kubernetes.Clientset, error) {
-	config, err := rest.InClusterConfig()
+type Options struct {
+	Kubeconfig string
+	Context    string
+	UserAgent  string
+	QPS        float32
+	Burst      int
+}
+
+func NewConfig(opts Options) (*rest.Config, error) {
+	config, err := loadConfig(opts.Kubeconfig, opts.Context)
 	if err != nil {
 		return nil, err
 	}
 
-	clientSet, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
+	if opts.QPS > 0 {
+		config.QPS = opts.QPS
 	}
-	return clientSet, nil
+	if opts.Burst > 0 {
+		config.Burst = opts.Burst
+	}
+	if opts.UserAgent != "" {
+		config.UserAgent = opts.UserAgent
+	}
+	return config, nil
 }
 
-func NewDynamicClientOrDie() (*dynamic.DynamicClient, error) {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, err
+func loadConfig(kubeconfig, context string) (*rest.Config, error) {
+	if kubeconfig != "" || context != "" || os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != "" {
+		return NewConfigFromKubeconfig(kubeconfig, context)
 	}
 
-	clientSet, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return nil, err
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		return config, nil
 	}
-	return clientSet, nil
+
+	slog.Info("kubeconfig", "source", clientcmd.RecommendedHomeFile, "reason", "in-cluster config not available", "err", err)
+	return NewConfigFromKubeconfig("", "")
+}
+
+func NewConfigFromKubeconfig(kubeconfig, context string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
+
+func NewClientForConfig(config *rest.Config) (*kubernetes.Clientset, error) {
+	return kubernetes.NewForConfig(config)
+}
+
+func NewDynamicClientForConfig(config *rest.Config) (*dynamic.DynamicClient, error) {
+	return dynamic.NewForConfig(config)
 }
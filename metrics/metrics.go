@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "downscaler"
+
+	DirectionDown = "down"
+	DirectionUp   = "up"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	TransitionDownscale = "downscale"
+	TransitionUpscale   = "upscale"
+)
+
+var (
+	Registry = prometheus.NewRegistry()
+
+	scaleOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scale_operations_total",
+		Help:      "Deployment scale operations by direction, result and namespace.",
+	}, []string{"direction", "result", "namespace"})
+
+	workloadsDownscaled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workloads_downscaled",
+		Help:      "Workloads currently downscaled by namespace.",
+	}, []string{"namespace"})
+
+	replicasSaved = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "replicas_saved",
+		Help:      "Replicas currently saved by the downscaled workloads by namespace.",
+	}, []string{"namespace"})
+
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Downscaler policy reloads by result.",
+	}, []string{"result"})
+
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Kubernetes API errors by resource and verb.",
+	}, []string{"resource", "verb"})
+
+	watcherRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "watcher_restarts_total",
+		Help:      "Restarts of the Downscaler policy watcher.",
+	})
+
+	dryRunActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dry_run_actions_total",
+		Help:      "Actions that would have been taken in dry run mode by action and namespace.",
+	}, []string{"action", "namespace"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_corrections_total",
+		Help:      "Deployments downscaled again after being scaled up during the down window by namespace.",
+	}, []string{"namespace"})
+
+	leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether this replica holds the leader election lease.",
+	})
+
+	nextTransitions = newTransitionCollector()
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		scaleOperations,
+		workloadsDownscaled,
+		replicasSaved,
+		configReloads,
+		apiErrors,
+		watcherRestarts,
+		dryRunActions,
+		driftCorrections,
+		leader,
+		nextTransitions,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+func ScaleOperation(namespace string, currentReplicas, desiredReplicas int32, err error) {
+	direction := DirectionUp
+	if desiredReplicas < currentReplicas {
+		direction = DirectionDown
+	}
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	scaleOperations.WithLabelValues(direction, result, namespace).Inc()
+}
+
+func NamespaceState(namespace string, apps shared.Apps) {
+	var workloads, replicas int
+	for _, workload := range apps.State {
+		if workload.Phase == shared.PhaseDownscaled {
+			workloads++
+			replicas += int(workload.OriginalReplicas)
+		}
+	}
+	workloadsDownscaled.WithLabelValues(namespace).Set(float64(workloads))
+	replicasSaved.WithLabelValues(namespace).Set(float64(replicas))
+}
+
+func NamespaceStates(stateByNamespace map[string]shared.Apps) {
+	for namespace, apps := range stateByNamespace {
+		NamespaceState(namespace, apps)
+	}
+}
+
+func ConfigReload(err error) {
+	if err != nil {
+		configReloads.WithLabelValues(ResultFailure).Inc()
+		return
+	}
+	configReloads.WithLabelValues(ResultSuccess).Inc()
+}
+
+func APIError(resource, verb string) {
+	apiErrors.WithLabelValues(resource, verb).Inc()
+}
+
+func WatcherRestart() {
+	watcherRestarts.Inc()
+}
+
+func DryRunAction(action, namespace string) {
+	dryRunActions.WithLabelValues(action, namespace).Inc()
+}
+
+func DriftCorrected(namespace string) {
+	driftCorrections.WithLabelValues(namespace).Inc()
+}
+
+func Leader(leading bool) {
+	if leading {
+		leader.Set(1)
+		return
+	}
+	leader.Set(0)
+}
+
+func RuleSchedule(policy, rule string, nextDownscale, nextUpscale time.Time) {
+	nextTransitions.set(policy, rule, nextDownscale, nextUpscale)
+}
+
+func ResetRuleSchedules(policy string) {
+	nextTransitions.reset(policy)
+}
+
+type transitionCollector struct {
+	mu       sync.Mutex
+	desc     *prometheus.Desc
+	policies map[string]map[string][2]time.Time
+	now      func() time.Time
+}
+
+func newTransitionCollector() *transitionCollector {
+	return &transitionCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "next_transition_seconds"),
+			"Seconds until the next downscale or upscale of each rule.",
+			[]string{"policy", "rule", "transition"}, nil,
+		),
+		policies: make(map[string]map[string][2]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (c *transitionCollector) set(policy, rule string, nextDownscale, nextUpscale time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.policies[policy]; !found {
+		c.policies[policy] = make(map[string][2]time.Time)
+	}
+	c.policies[policy][rule] = [2]time.Time{nextDownscale, nextUpscale}
+}
+
+func (c *transitionCollector) reset(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.policies, policy)
+}
+
+func (c *transitionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *transitionCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for policy, rules := range c.policies {
+		for rule, next := range rules {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, next[0].Sub(now).Seconds(), policy, rule, TransitionDownscale)
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, next[1].Sub(now).Seconds(), policy, rule, TransitionUpscale)
+		}
+	}
+}
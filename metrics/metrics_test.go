@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNamespaceState(t *testing.T) {
+	tests := []struct {
+		name              string
+		apps              shared.Apps
+		expectedWorkloads float64
+		expectedReplicas  float64
+	}{
+		{"Downscaled and upscaled workloads", shared.Apps{State: []shared.Workload{
+			{Name: "nginx", OriginalReplicas: 3, Phase: shared.PhaseDownscaled},
+			{Name: "redis", OriginalReplicas: 2, Phase: shared.PhaseDownscaled},
+			{Name: "api", OriginalReplicas: 5, Phase: shared.PhaseUpscaled},
+		}}, 2, 5},
+		{"Every workload upscaled", shared.Apps{State: []shared.Workload{
+			{Name: "nginx", OriginalReplicas: 3, Phase: shared.PhaseUpscaled},
+		}}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NamespaceState("nginx-2", tt.apps)
+
+			if workloads := testutil.ToFloat64(workloadsDownscaled.WithLabelValues("nginx-2")); workloads != tt.expectedWorkloads {
+				t.Errorf("workloads_downscaled = %v; expected %v", workloads, tt.expectedWorkloads)
+			}
+			if replicas := testutil.ToFloat64(replicasSaved.WithLabelValues("nginx-2")); replicas != tt.expectedReplicas {
+				t.Errorf("replicas_saved = %v; expected %v", replicas, tt.expectedReplicas)
+			}
+		})
+	}
+}
+
+func TestTransitionCollector(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+
+	collector := newTransitionCollector()
+	collector.now = func() time.Time { return now }
+	collector.set("downscaler", "nginx-2 01:30-14:50", now.Add(2*time.Hour+50*time.Minute), now.Add(13*time.Hour+30*time.Minute))
+	collector.set("team-a/downscaler", "team-a 08:00-20:00", now, now)
+	collector.reset("team-a/downscaler")
+
+	expected := `
+# HELP downscaler_next_transition_seconds Seconds until the next downscale or upscale of each rule.
+# TYPE downscaler_next_transition_seconds gauge
+downscaler_next_transition_seconds{policy="downscaler",rule="nginx-2 01:30-14:50",transition="downscale"} 10200
+downscaler_next_transition_seconds{policy="downscaler",rule="nginx-2 01:30-14:50",transition="upscale"} 48600
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	v1 "k8s.io/api/apps/v1"
+)
+
+type drift int
+
+const (
+	driftNone drift = iota
+	driftScaled
+	driftRedeployed
+)
+
+func (c *Scheduler) enforceDownWindow(task SchedulerTask, namespaces []string) {
+	if !c.input.Enforce || !c.input.RunUpscaling || c.input.DryRun {
+		return
+	}
+
+	for _, namespace := range namespaces {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored {
+			continue
+		}
+
+		namespaceState, err := c.State.Get(c.ctx, namespace)
+		if errors.Is(err, state.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.Error("enforce", "namespace", namespace, "verb", "read state", "err", err)
+			continue
+		}
+
+		apps := namespaceState.Apps
+		apps.State = append([]shared.Workload{}, namespaceState.Apps.State...)
+
+		deployments, err := c.Kubernetes.GetDeployments(c.ctx, namespace)
+		if err != nil {
+			continue
+		}
+
+		changed := false
+		for _, deployment := range deployments.Items {
+			if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+				c.forgetDriftCorrection(&deployment)
+				continue
+			}
+			if c.driftAlreadyCorrected(&deployment) {
+				continue
+			}
+
+			for i, workload := range apps.State {
+				if !workload.Matches(deployment.Name, string(deployment.UID)) || workload.Phase != shared.PhaseDownscaled {
+					continue
+				}
+
+				replicas := *deployment.Spec.Replicas
+				templateHash := kas.TemplateHash(&deployment)
+
+				detected := detectDrift(workload, replicas, templateHash)
+				if detected == driftRedeployed {
+					workload.OriginalReplicas = replicas
+					workload.TemplateHash = templateHash
+					apps.State[i] = workload
+					changed = true
+				}
+
+				slog.Warn("enforce", "name", deployment.Name, "namespace", namespace, "replicas", replicas, "redeployed", detected == driftRedeployed, "original replicas", workload.OriginalReplicas, "action", "downscaling again")
+				c.Events.DriftCorrected(&deployment, replicas, workload.OriginalReplicas, detected == driftRedeployed, task.Name())
+				metrics.DriftCorrected(namespace)
+
+				patch, err := kas.GenerateScalePatch(0)
+				if err != nil {
+					slog.Error("enforce", "name", deployment.Name, "namespace", namespace, "verb", "generate patch", "err", err)
+					continue
+				}
+				if err := c.Kubernetes.ScaleDeployments(c.ctx, namespace, &deployment, patch, 0, task.Name()); err == nil {
+					c.rememberDriftCorrection(&deployment)
+				}
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		ctx, cancel := c.persistContext()
+		err = c.writeStateByNamespace(ctx, map[string]shared.Apps{namespace: apps}, map[string]*state.NamespaceState{namespace: namespaceState})
+		cancel()
+		if err != nil {
+			slog.Error("enforce", "namespace", namespace, "verb", "write state", "err", err)
+			c.reportNamespaceError(task, []string{namespace}, err)
+			continue
+		}
+		metrics.NamespaceState(namespace, apps)
+	}
+}
+
+// the informer can still serve the drifted deployment right after it was downscaled again,
+// so a drift is corrected once for the resource version it was detected on
+func (c *Scheduler) driftAlreadyCorrected(deployment *v1.Deployment) bool {
+	c.driftMu.Lock()
+	defer c.driftMu.Unlock()
+	version, found := c.driftCorrected[driftKey(deployment)]
+	return found && version == deployment.ResourceVersion
+}
+
+func (c *Scheduler) rememberDriftCorrection(deployment *v1.Deployment) {
+	c.driftMu.Lock()
+	defer c.driftMu.Unlock()
+	if c.driftCorrected == nil {
+		c.driftCorrected = make(map[string]string)
+	}
+	c.driftCorrected[driftKey(deployment)] = deployment.ResourceVersion
+}
+
+func (c *Scheduler) forgetDriftCorrection(deployment *v1.Deployment) {
+	c.driftMu.Lock()
+	defer c.driftMu.Unlock()
+	delete(c.driftCorrected, driftKey(deployment))
+}
+
+func driftKey(deployment *v1.Deployment) string {
+	return deployment.Namespace + "/" + deployment.Name
+}
+
+func detectDrift(workload shared.Workload, replicas int32, templateHash string) drift {
+	if workload.Phase != shared.PhaseDownscaled || replicas == 0 {
+		return driftNone
+	}
+	if workload.TemplateHash != "" && workload.TemplateHash != templateHash {
+		return driftRedeployed
+	}
+	return driftScaled
+}
@@ -1,12 +1,6 @@
 package scheduler
 
 const (
-	ErrDefaultUpTimeNotFound          = "default uptime is missing"
-	ErrTimeZoneNotFound               = "time zone is missing"
-	ErrRecurrenceTimeNotFound         = "recurrence time is missing"
-	ErrNotValidExpressionKey          = "not valid expression key"
-	ErrNotValidExpressionOperator     = "not valid expression operator"
-	ErrExpressionsValuesAreEmpty      = "the expression values are empty"
-	ErrEmptyRules                     = "empty rules - did you provide any?"
 	ErrNamespaceFromConfigDoNotExists = "the provided namespace from the yaml do not exists in the kubernetes cluster"
+	ErrDrainDeadlineExceeded          = "the in-flight scaling did not finish before the shutdown deadline"
 )
@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/status"
+)
+
+func resolveOverlaps(rules shared.DownscalerRules) (shared.DownscalerRules, []status.RuleOverlap) {
+	claims := make(map[string][]int)
+	ordered := make([]string, 0)
+	for i, rule := range rules.Rules {
+		for _, namespace := range rule.Namespaces {
+			claimedBy, found := claims[namespace]
+			if !found {
+				ordered = append(ordered, namespace)
+			} else if claimedBy[len(claimedBy)-1] == i {
+				continue
+			}
+			claims[namespace] = append(claimedBy, i)
+		}
+	}
+
+	resolution := rules.OverlapResolution
+	if resolution == "" || resolution == shared.OverlapReject {
+		resolution = shared.OverlapFirstRule
+	}
+
+	winners := make(map[string]int)
+	overlaps := make([]status.RuleOverlap, 0)
+	for _, namespace := range ordered {
+		claimedBy := claims[namespace]
+		if len(claimedBy) < 2 {
+			continue
+		}
+
+		winner := pickRule(rules.Rules, claimedBy, resolution)
+		winners[namespace] = winner
+
+		windows := make([]string, len(claimedBy))
+		for i, rule := range claimedBy {
+			windows[i] = rules.Rules[rule].WithCron
+		}
+		overlaps = append(overlaps, status.RuleOverlap{
+			Namespace:  namespace,
+			Rules:      windows,
+			Winner:     rules.Rules[winner].WithCron,
+			Resolution: resolution,
+		})
+	}
+	if len(overlaps) == 0 {
+		return rules, nil
+	}
+
+	resolved := shared.DownscalerRules{OverlapResolution: rules.OverlapResolution}
+	for i, rule := range rules.Rules {
+		namespaces := make([]string, 0, len(rule.Namespaces))
+		for _, namespace := range rule.Namespaces {
+			if winner, overlapping := winners[namespace]; overlapping && winner != i {
+				continue
+			}
+			namespaces = append(namespaces, namespace)
+		}
+		if len(namespaces) == 0 {
+			continue
+		}
+		rule.Namespaces = namespaces
+		resolved.Rules = append(resolved.Rules, rule)
+	}
+	return resolved, overlaps
+}
+
+func pickRule(rules []shared.Rule, claimedBy []int, resolution string) int {
+	winner := claimedBy[0]
+	for _, i := range claimedBy[1:] {
+		switch resolution {
+		case shared.OverlapMostRestrictive:
+			if ruleUptime(rules[i]) < ruleUptime(rules[winner]) {
+				winner = i
+			}
+		case shared.OverlapLongestUptime:
+			if ruleUptime(rules[i]) > ruleUptime(rules[winner]) {
+				winner = i
+			}
+		}
+	}
+	return winner
+}
+
+func ruleUptime(rule shared.Rule) time.Duration {
+	targetTimeToUpscale, targetTimeToDownscale := extractUpscalingAndDownscalingTime(rule.WithCron, time.UTC)
+	uptime := targetTimeToDownscale.Sub(targetTimeToUpscale)
+	if uptime < 0 {
+		uptime += 24 * time.Hour
+	}
+	return uptime
+}
+
+func logOverlaps(overlaps []status.RuleOverlap) {
+	for _, overlap := range overlaps {
+		slog.Warn("overlap", "namespace", overlap.Namespace, "rules", overlap.Rules, "resolution", overlap.Resolution, "winner", overlap.Winner, "status", "scheduled by the winner only")
+	}
+}
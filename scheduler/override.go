@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	"github.com/adalbertjnr/downscaler/status"
+)
+
+func (c *Scheduler) handleOverrides(task SchedulerTask, namespaces []string, now, targetTimeToUpscale, targetTimeToDownscale time.Time) []string {
+	regular, overrides := c.splitOverriddenNamespaces(namespaces, now)
+	if len(overrides) == 0 {
+		return regular
+	}
+
+	scheduledDown := scheduledDownByTime(now, targetTimeToUpscale, targetTimeToDownscale, parseRecurrence(task.Recurrence))
+	if len(regular) > 0 {
+		if currentReplicasState, err := c.inspectReplicasStateByNamespace(c.ctx, regular); err == nil {
+			scheduledDown = currentReplicasState == shared.DeploymentsWithDownscaledState
+		}
+	}
+
+	c.reconcileOverrides(task, overrides, scheduledDown, now)
+	return regular
+}
+
+func (c *Scheduler) splitOverriddenNamespaces(namespaces []string, now time.Time) ([]string, map[string]shared.Override) {
+	overrides := c.Kubernetes.GetNamespaceOverrides(c.ctx, namespaces)
+
+	regular := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		if override, found := overrides[namespace]; found && override.Active(now) {
+			continue
+		}
+		regular = append(regular, namespace)
+	}
+	return regular, overrides
+}
+
+func (c *Scheduler) reconcileOverrides(task SchedulerTask, overrides map[string]shared.Override, scheduledDown bool, now time.Time) {
+	for namespace, override := range overrides {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored {
+			continue
+		}
+
+		if !override.Active(now) {
+			slog.Info("override", "namespace", namespace, "mode", override.Mode, "status", "expired", "action", "returning to the schedule")
+			if c.scaleNamespaceTo(task, namespace, scheduledDown, task.Name()) {
+				_ = c.Kubernetes.PatchNamespaceOverride(c.ctx, namespace, nil)
+			}
+			continue
+		}
+
+		down := override.Mode == shared.OverrideSleep
+		if c.scaleNamespaceTo(task, namespace, down, override.String()) {
+			phase := status.PhaseUp
+			if down {
+				phase = status.PhaseDown
+			}
+			c.Status.NamespacePhase(namespace, task.Name(), phase, override.String())
+		}
+	}
+}
+
+func (c *Scheduler) scaleNamespaceTo(task SchedulerTask, namespace string, down bool, scaledBy string) bool {
+	namespaceState, err := c.State.Get(c.ctx, namespace)
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		slog.Error("error reading the state", "namespace", namespace, "error", err)
+		return false
+	}
+
+	downscaled := namespaceState != nil && hasDownscaledWorkloads(namespaceState.Apps)
+	readVersions := map[string]*state.NamespaceState{}
+	if namespaceState != nil {
+		readVersions[namespace] = namespaceState
+	}
+
+	var scaledState map[string]shared.Apps
+	switch {
+	case down && !downscaled:
+		notUsableNamespaces := shared.NotUsableNamespacesDuringScheduling{
+			IgnoredNamespaces:   c.IgnoredNamespaces,
+			ScheduledNamespaces: task.ScheduledNamespaces,
+		}
+		scaledState = c.Kubernetes.StartDownscaling(c.ctx, []string{namespace}, notUsableNamespaces, scaledBy)
+	case !down && downscaled:
+		appsByNamespace := map[string]shared.Apps{namespace: namespaceState.Apps}
+		scaledState = c.Kubernetes.StartUpscaling(c.ctx, appsByNamespace, []string{namespace}, shared.UpscaleSpread{}, scaledBy)
+	default:
+		return true
+	}
+
+	ctx, cancel := c.persistContext()
+	defer cancel()
+
+	if err := c.writeStateByNamespace(ctx, scaledState, readVersions); err != nil {
+		slog.Error("error writing state after override", "namespace", namespace, "err", err)
+		return false
+	}
+	return true
+}
+
+func hasDownscaledWorkloads(apps shared.Apps) bool {
+	for _, workload := range apps.State {
+		if workload.Phase == shared.PhaseDownscaled {
+			return true
+		}
+	}
+	return false
+}
+
+func scheduledDownByTime(now, targetTimeToUpscale, targetTimeToDownscale time.Time, recurrenceDays []time.Weekday) bool {
+	if now.Before(targetTimeToUpscale) {
+		return isWeekdayIn(now.AddDate(0, 0, -1).Weekday(), recurrenceDays)
+	}
+	return now.After(targetTimeToDownscale) && isWeekdayIn(now.Weekday(), recurrenceDays)
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+const (
+	TransitionDownscale = "downscale"
+	TransitionUpscale   = "upscale"
+)
+
+type PlannedTransition struct {
+	Time       time.Time
+	Transition string
+	Namespace  string
+	Rule       string
+}
+
+func Plan(downscalerData *shared.DownscalerPolicy, from, to time.Time) ([]PlannedTransition, error) {
+	var (
+		timeBlock  = downscalerData.Spec.ExecutionOpts.Time
+		expression = timeBlock.Downscaler.DownscalerSelectorTerms.MatchExpressions
+		timeRules  = timeBlock.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules
+	)
+
+	location, err := time.LoadLocation(timeBlock.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("not possible to load the location. err %v", err)
+	}
+	recurrenceDays := parseRecurrence(timeBlock.Recurrence)
+
+	excluded := make(map[string]struct{}, len(expression.Values))
+	for _, namespace := range expression.Values {
+		excluded[namespace] = struct{}{}
+	}
+
+	rules, _ := resolveOverlaps(shared.DownscalerRules{Rules: timeRules.Rules, OverlapResolution: timeRules.OverlapResolution})
+
+	transitions := make([]PlannedTransition, 0)
+	for _, rule := range rules.Rules {
+		task := SchedulerTask{Rules: Rules{Namespaces: rule.Namespaces, WithCron: rule.WithCron}}
+
+		targetTimeToUpscale, targetTimeToDownscale := extractUpscalingAndDownscalingTime(rule.WithCron, location)
+		if targetTimeToUpscale.IsZero() || targetTimeToDownscale.IsZero() {
+			return nil, fmt.Errorf("rule %s: invalid withCron %q", task.Name(), rule.WithCron)
+		}
+
+		start := from.In(location)
+		for day := time.Date(start.Year(), start.Month(), start.Day()-1, 0, 0, 0, 0, location); !day.After(to); day = day.AddDate(0, 0, 1) {
+			if !isWeekdayIn(day.Weekday(), recurrenceDays) {
+				continue
+			}
+
+			downscaleAt := atClock(day, targetTimeToDownscale)
+			upscaleAt := atClock(day, targetTimeToUpscale)
+			for !upscaleAt.After(downscaleAt) {
+				upscaleAt = upscaleAt.AddDate(0, 0, 1)
+			}
+
+			for _, namespace := range rule.Namespaces {
+				if _, found := excluded[namespace]; found {
+					continue
+				}
+				for _, transition := range []PlannedTransition{
+					{Time: downscaleAt, Transition: TransitionDownscale, Namespace: namespace, Rule: task.Name()},
+					{Time: upscaleAt, Transition: TransitionUpscale, Namespace: namespace, Rule: task.Name()},
+				} {
+					if !transition.Time.Before(from) && !transition.Time.After(to) {
+						transitions = append(transitions, transition)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(transitions, func(i, j int) bool {
+		if transitions[i].Namespace != transitions[j].Namespace {
+			return transitions[i].Namespace < transitions[j].Namespace
+		}
+		return transitions[i].Time.Before(transitions[j].Time)
+	})
+	return transitions, nil
+}
+
+func atClock(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	v1 "k8s.io/api/apps/v1"
+)
+
+type reconcileDecision struct {
+	workload shared.Workload
+	scale    bool
+	replicas int32
+	changed  bool
+}
+
+func (c *Scheduler) reconcileNamespaces(task SchedulerTask, namespaces []string, scheduledDown bool) bool {
+	if !c.input.RunUpscaling {
+		return false
+	}
+
+	reconciled := false
+	for _, namespace := range namespaces {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored {
+			continue
+		}
+
+		namespaceState, err := c.State.Get(c.ctx, namespace)
+		if errors.Is(err, state.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.Error("reconcile", "namespace", namespace, "verb", "read state", "err", err)
+			continue
+		}
+
+		deployments, err := c.Kubernetes.GetDeployments(c.ctx, namespace)
+		if err != nil {
+			continue
+		}
+
+		live := make(map[string]*v1.Deployment)
+		for _, deployment := range deployments.Items {
+			live[deployment.Name] = &deployment
+		}
+
+		if !needsReconcile(namespaceState.Apps, live) {
+			continue
+		}
+
+		apps, changed := c.reconcileWorkloads(namespace, namespaceState.Apps, live, scheduledDown, task.Name())
+		if !changed {
+			continue
+		}
+
+		ctx, cancel := c.persistContext()
+		err = c.writeStateByNamespace(ctx, map[string]shared.Apps{namespace: apps}, map[string]*state.NamespaceState{namespace: namespaceState})
+		cancel()
+		if err != nil {
+			slog.Error("reconcile", "namespace", namespace, "verb", "write state", "err", err)
+			c.reportNamespaceError(task, []string{namespace}, err)
+			continue
+		}
+		metrics.NamespaceState(namespace, apps)
+		reconciled = true
+	}
+	return reconciled
+}
+
+func (c *Scheduler) reconcileWorkloads(namespace string, apps shared.Apps, live map[string]*v1.Deployment, scheduledDown bool, scaledBy string) (shared.Apps, bool) {
+	reconciled := apps
+	reconciled.State = make([]shared.Workload, len(apps.State))
+
+	changed := false
+	for i, workload := range apps.State {
+		reconciled.State[i] = workload
+
+		deployment, found := live[workload.Name]
+		if !found || deployment.Spec.Replicas == nil || !workload.Matches(deployment.Name, string(deployment.UID)) {
+			continue
+		}
+
+		decision := reconcileWorkload(workload, *deployment.Spec.Replicas, scheduledDown, scaledBy, time.Now().UTC())
+		if decision.scale {
+			patch, err := kas.GenerateScalePatch(decision.replicas)
+			if err != nil {
+				slog.Error("reconcile", "name", workload.Name, "namespace", namespace, "verb", "generate patch", "err", err)
+				continue
+			}
+			slog.Info("reconcile", "name", workload.Name, "namespace", namespace, "recorded phase", workload.Phase, "live replicas", *deployment.Spec.Replicas, "desired replicas", decision.replicas)
+			if err := c.Kubernetes.ScaleDeployments(c.ctx, namespace, deployment, patch, decision.replicas, scaledBy); err != nil {
+				if workload.LastError != err.Error() {
+					reconciled.State[i].LastError = err.Error()
+					changed = true
+				}
+				continue
+			}
+		}
+		if decision.changed || (decision.scale && workload.Failed()) {
+			decision.workload.LastError = ""
+			if decision.workload.Phase == shared.PhaseDownscaled {
+				decision.workload.TemplateHash = kas.TemplateHash(deployment)
+			}
+			reconciled.State[i] = decision.workload
+			changed = true
+		}
+	}
+	return reconciled, changed
+}
+
+func needsReconcile(apps shared.Apps, live map[string]*v1.Deployment) bool {
+	downscaled, total := countDownscaledWorkloads(apps.State)
+	if downscaled > 0 && downscaled < total {
+		return true
+	}
+
+	for _, workload := range apps.State {
+		deployment, found := live[workload.Name]
+		if !found || deployment.Spec.Replicas == nil || !workload.Matches(deployment.Name, string(deployment.UID)) {
+			continue
+		}
+		if workload.Phase == shared.PhaseDownscaled && *deployment.Spec.Replicas > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func reconcileWorkload(workload shared.Workload, liveReplicas int32, scheduledDown bool, scaledBy string, now time.Time) reconcileDecision {
+	downscaled := workload.Phase == shared.PhaseDownscaled
+
+	switch {
+	case scheduledDown && downscaled && liveReplicas > 0:
+		return reconcileDecision{workload: workload, scale: true, replicas: 0}
+
+	case scheduledDown && !downscaled && liveReplicas > 0:
+		workload.OriginalReplicas = liveReplicas
+		workload.Phase = shared.PhaseDownscaled
+		workload.ScaledAt = now
+		workload.ScaledBy = scaledBy
+		return reconcileDecision{workload: workload, scale: true, replicas: 0, changed: true}
+
+	case !scheduledDown && downscaled:
+		scale := liveReplicas == 0 && workload.OriginalReplicas > 0
+		workload.Phase = shared.PhaseUpscaled
+		workload.ScaledAt = now
+		workload.ScaledBy = scaledBy
+		return reconcileDecision{workload: workload, scale: scale, replicas: workload.OriginalReplicas, changed: true}
+	}
+	return reconcileDecision{workload: workload}
+}
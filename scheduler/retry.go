@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+func (c *Scheduler) retryFailedScaling(task SchedulerTask, namespaces []string, now, targetTimeToUpscale, targetTimeToDownscale time.Time) {
+	if !c.input.RunUpscaling {
+		return
+	}
+
+	scheduledDown := scheduledDownByTime(now, targetTimeToUpscale, targetTimeToDownscale, parseRecurrence(task.Recurrence))
+	_, overrides := c.splitOverriddenNamespaces(namespaces, now)
+
+	stateByNamespace := c.readStateByNamespace(c.ctx, namespaces)
+	retriedState := make(map[string]shared.Apps)
+	failedByNamespace := make(map[string]shared.Apps)
+	for namespace, namespaceState := range stateByNamespace {
+		wantedDown := scheduledDown
+		if override, found := overrides[namespace]; found && override.Active(now) {
+			wantedDown = override.Mode == shared.OverrideSleep
+		}
+
+		apps, dropped := dropStaleRetries(namespace, namespaceState.Apps, wantedDown)
+		if dropped {
+			retriedState[namespace] = apps
+		}
+		if hasFailedWorkloads(apps) {
+			failedByNamespace[namespace] = apps
+		}
+	}
+	if len(retriedState) == 0 && len(failedByNamespace) == 0 {
+		return
+	}
+
+	if len(failedByNamespace) > 0 {
+		for namespace, apps := range c.Kubernetes.RetryFailedScaling(c.ctx, failedByNamespace, task.Name()) {
+			retriedState[namespace] = apps
+		}
+	}
+
+	ctx, cancel := c.persistContext()
+	err := c.writeStateByNamespace(ctx, retriedState, stateByNamespace)
+	cancel()
+	if err != nil {
+		slog.Error("error writing state after retrying", "err", err)
+		return
+	}
+	metrics.NamespaceStates(retriedState)
+}
+
+// a failed scaling is only retried while the window still wants the phase it was heading to,
+// once the window flips the pending retry is dropped and the workload follows the schedule
+func dropStaleRetries(namespace string, apps shared.Apps, scheduledDown bool) (shared.Apps, bool) {
+	wanted := shared.PhaseUpscaled
+	if scheduledDown {
+		wanted = shared.PhaseDownscaled
+	}
+
+	updated := apps
+	updated.State = append([]shared.Workload{}, apps.State...)
+	dropped := false
+	for i, workload := range updated.State {
+		if !workload.Failed() || workload.TargetPhase() == wanted {
+			continue
+		}
+		slog.Info("retry", "name", workload.Name, "namespace", namespace, "phase", workload.Phase, "last error", workload.LastError, "status", "dropped", "reason", "window changed")
+		updated.State[i].LastError = ""
+		dropped = true
+	}
+	return updated, dropped
+}
+
+func hasFailedWorkloads(apps shared.Apps) bool {
+	for _, workload := range apps.State {
+		if workload.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Scheduler) stateFailures(namespaces []string) (failedByNamespace, unparsedByNamespace map[string][]string) {
+	failedByNamespace = make(map[string][]string)
+	unparsedByNamespace = make(map[string][]string)
+	if !c.input.RunUpscaling {
+		return failedByNamespace, unparsedByNamespace
+	}
+
+	for namespace, namespaceState := range c.readStateByNamespace(c.ctx, namespaces) {
+		for _, workload := range namespaceState.Apps.State {
+			if workload.Failed() {
+				failedByNamespace[namespace] = append(failedByNamespace[namespace], workload.Name)
+			}
+		}
+		if len(namespaceState.Apps.Unparsed) > 0 {
+			unparsedByNamespace[namespace] = namespaceState.Apps.Unparsed
+		}
+	}
+	return failedByNamespace, unparsedByNamespace
+}
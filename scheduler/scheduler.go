@@ -2,24 +2,29 @@ package scheduler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/adalbertjnr/downscaler/common"
+	"github.com/adalbertjnr/downscaler/events"
+	"github.com/adalbertjnr/downscaler/health"
 	"github.com/adalbertjnr/downscaler/input"
 	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/metrics"
 	"github.com/adalbertjnr/downscaler/shared"
-	"gopkg.in/yaml.v2"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/adalbertjnr/downscaler/state"
+	"github.com/adalbertjnr/downscaler/status"
+	"github.com/adalbertjnr/downscaler/validation"
 )
 
 type Rules struct {
 	Namespaces          []string
 	WithCron            string
 	Recurrence          string
+	UpscaleSpread       shared.UpscaleSpread
 	ScheduledNamespaces map[string]struct{}
 }
 
@@ -27,64 +32,227 @@ type SchedulerTask struct {
 	Rules
 }
 
+type taskRoutine struct {
+	stopch chan struct{}
+	cancel context.CancelFunc
+}
+
 type Scheduler struct {
 	Kubernetes        kas.Kubernetes
+	State             state.StateStore
+	Status            *status.Reporter
+	Events            *events.Recorder
+	Health            *health.Checker
 	Location          *time.Location
 	Tasks             []SchedulerTask
 	Recurrence        string
 	IgnoredNamespaces map[string]struct{}
-	taskRoutines      map[string]chan struct{}
+	ClaimedNamespaces map[string]struct{}
+	taskRoutines      map[string]taskRoutine
+	routinesMu        sync.Mutex
+	routines          sync.WaitGroup
+	driftMu           sync.Mutex
+	driftCorrected    map[string]string
 	taskch            chan []SchedulerTask
+	tasksUpdated      chan struct{}
 	stopch            chan struct{}
 	input             *input.FromArgs
+	policyName        string
 	ctx               context.Context
+	abort             context.CancelFunc
 }
 
 func NewScheduler() *Scheduler {
+	ctx, abort := context.WithCancel(context.Background())
 	return &Scheduler{
 		taskch:       make(chan []SchedulerTask),
+		tasksUpdated: make(chan struct{}),
 		stopch:       make(chan struct{}),
-		taskRoutines: make(map[string]chan struct{}),
-		ctx:          context.Background(),
+		taskRoutines: make(map[string]taskRoutine),
+		ctx:          ctx,
+		abort:        abort,
 	}
 }
 
+func (c *Scheduler) AddContext(ctx context.Context) *Scheduler {
+	c.ctx, c.abort = context.WithCancel(context.WithoutCancel(ctx))
+	return c
+}
+
 func (c *Scheduler) AddKubeApiSvc(client kas.Kubernetes) *Scheduler {
 	c.Kubernetes = client
 	return c
 }
 
+func (c *Scheduler) AddStateStore(store state.StateStore) *Scheduler {
+	c.State = store
+	return c
+}
+
+func (c *Scheduler) AddEventRecorder(recorder *events.Recorder) *Scheduler {
+	c.Events = recorder
+	return c
+}
+
+func (c *Scheduler) AddHealthChecker(checker *health.Checker) *Scheduler {
+	c.Health = checker
+	return c
+}
+
 func (c *Scheduler) AddInput(input *input.FromArgs) *Scheduler {
 	c.input = input
 	return c
 }
 
+func (c *Scheduler) AddPolicyName(name string) *Scheduler {
+	c.policyName = name
+	return c
+}
+
+func (c *Scheduler) AddClaimedNamespaces(namespaces map[string]struct{}) *Scheduler {
+	c.ClaimedNamespaces = namespaces
+	return c
+}
+
+func (c *Scheduler) ReportConflicts(downscalerData *shared.DownscalerPolicy, conflicts []string) {
+	c.Status.NamespaceConflicts(conflicts)
+	if len(conflicts) > 0 {
+		for _, conflict := range conflicts {
+			slog.Warn("scheduler", "kind", downscalerData.Kind, "name", downscalerData.Key(), "conflict", conflict)
+		}
+		c.Events.PolicyConflict(downscalerData, conflicts)
+	}
+}
+
+func (c *Scheduler) StopTasks() {
+	c.killCurrentSchedulerRoutines()
+	c.routines.Wait()
+}
+
+func (c *Scheduler) Stop() {
+	close(c.stopch)
+}
+
+func (c *Scheduler) Drain(timeout time.Duration) error {
+	c.stopCurrentSchedulerRoutines(false)
+	defer c.flushStatus()
+
+	if c.waitRoutines(timeout) {
+		return nil
+	}
+
+	slog.Warn("scheduler", "status", "shutdown deadline exceeded", "timeout", timeout.String(), "action", "aborting the in-flight scaling")
+	c.abort()
+	if !c.waitRoutines(shared.StateFlushTimeout) {
+		slog.Error("scheduler", "status", "tasks still running", "action", "exiting without their state")
+	}
+	return errors.New(ErrDrainDeadlineExceeded)
+}
+
+func (c *Scheduler) waitRoutines(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.routines.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (c *Scheduler) flushStatus() {
+	ctx, cancel := c.persistContext()
+	defer cancel()
+	c.Status.Flush(ctx)
+}
+
+func (c *Scheduler) persistContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(c.ctx), shared.StateFlushTimeout)
+}
+
 func (c *Scheduler) AddSchedulerDetails(downscalerData *shared.DownscalerPolicy) {
-	if errors := c.Validate(downscalerData); len(errors) > 0 {
+	if errs := validation.Policy(downscalerData); len(errs) > 0 {
+		errors := validation.Messages(errs)
 		for _, err := range errors {
 			slog.Error("crontime validator", "error", err)
 		}
+		c.Status.PolicyInvalid(downscalerData.Metadata.Generation, errors)
+		c.Events.PolicyInvalid(downscalerData, errors)
+		metrics.ConfigReload(fmt.Errorf("%d validation error(s)", len(errors)))
 		return
 	}
 
 	var (
 		expression = downscalerData.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions
-		rules      = downscalerData.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules
+		timeRules  = downscalerData.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules
 		recurrence = downscalerData.Spec.ExecutionOpts.Time.Recurrence
 		timezone   = downscalerData.Spec.ExecutionOpts.Time.TimeZone
 	)
 
 	c.updateRecurrenceIfEmpty(recurrence)
 	if err := c.updateTimeZoneIfNotEqual(timezone); err != nil {
+		c.Status.PolicyInvalid(downscalerData.Metadata.Generation, []string{err.Error()})
+		c.Events.PolicyInvalid(downscalerData, []string{err.Error()})
+		metrics.ConfigReload(err)
 		return
 	}
 
+	rules, overlaps := resolveOverlaps(shared.DownscalerRules{Rules: timeRules.Rules, OverlapResolution: timeRules.OverlapResolution})
+	logOverlaps(overlaps)
+
 	c.parseSchedulerConfig(
 		recurrence,
 		shared.DownscalerExpression{MatchExpressions: expression},
-		shared.DownscalerRules{Rules: rules},
+		rules,
 	)
 
+	c.Status.PolicyApplied(downscalerData.Metadata.Generation, rulesByName(rules))
+	c.Status.RuleOverlaps(overlaps)
+	c.Events.PolicyApplied(downscalerData, len(rules.Rules))
+	metrics.ResetRuleSchedules(c.policyName)
+	metrics.ConfigReload(nil)
+	c.Health.PolicyApplied(downscalerData.Key())
+}
+
+func (c *Scheduler) RemoveSchedulerDetails(downscalerData *shared.DownscalerPolicy) {
+	slog.Warn("scheduler", "kind", downscalerData.Kind, "name", downscalerData.Key(), "status", "deleted", "action", "stopping the tasks")
+
+	c.StopTasks()
+
+	metrics.ResetRuleSchedules(c.policyName)
+	c.Status.PolicyRemoved()
+	c.Health.PolicyRemoved(downscalerData.Key())
+
+	if c.input.RunUpscaling && c.input.UpscaleOnDelete {
+		c.upscaleDownscaledNamespaces(downscalerData, shared.ScaledByDownscalerDeleted)
+	}
+	slog.Info("scheduler", "kind", downscalerData.Kind, "name", downscalerData.Key(), "status", "idle", "action", "waiting for a new Downscaler")
+}
+
+func (c *Scheduler) upscaleDownscaledNamespaces(downscalerData *shared.DownscalerPolicy, scaledBy string) {
+	states, err := c.State.List(c.ctx)
+	if err != nil {
+		slog.Error("error listing the state", "error", err)
+		return
+	}
+
+	for _, namespaceState := range states {
+		if !hasDownscaledWorkloads(namespaceState.Apps) {
+			continue
+		}
+		if _, claimed := c.ClaimedNamespaces[namespaceState.Namespace]; claimed {
+			continue
+		}
+		if downscalerData.Kind == shared.NamespaceDownscalerKind && namespaceState.Namespace != downscalerData.Metadata.Namespace {
+			continue
+		}
+		slog.Info("upscaling", "namespace", namespaceState.Namespace, "reason", scaledBy)
+		c.scaleNamespaceTo(SchedulerTask{}, namespaceState.Namespace, false, scaledBy)
+	}
 }
 
 func (c *Scheduler) parseSchedulerConfig(
@@ -109,18 +277,23 @@ func (c *Scheduler) parseSchedulerConfig(
 		tasks := make([]SchedulerTask, len(rules.Rules))
 
 		scheduledNamespaces := separatedScheduledNamespaces(rules)
+		for namespace := range c.ClaimedNamespaces {
+			scheduledNamespaces[namespace] = struct{}{}
+		}
 		for i, crit := range rules.Rules {
 			tasks[i] = SchedulerTask{
 				Rules: Rules{
 					Namespaces:          crit.Namespaces,
 					WithCron:            crit.WithCron,
 					Recurrence:          c.Recurrence,
+					UpscaleSpread:       parseUpscaleSpread(crit),
 					ScheduledNamespaces: scheduledNamespaces,
 				},
 			}
 		}
 		c.killCurrentSchedulerRoutines()
 		c.taskch <- tasks
+		<-c.tasksUpdated
 	}
 }
 
@@ -130,43 +303,50 @@ func (c *Scheduler) StartScheduler() {
 
 func (c *Scheduler) runSchedulerLoop() {
 	for {
-		if tasks, open := <-c.taskch; open {
+		select {
+		case tasks := <-c.taskch:
 			c.updateTasks(tasks)
+			c.tasksUpdated <- struct{}{}
+		case <-c.stopch:
+			return
 		}
-		time.Sleep(time.Second * 5)
-		continue
 	}
 }
 
 func (c *Scheduler) updateTasks(tasks []SchedulerTask) {
-	newTaskMap := make(map[string]SchedulerTask)
+	c.routinesMu.Lock()
+	defer c.routinesMu.Unlock()
 
 	for _, task := range tasks {
 		key := task.Rules.WithCron + strings.Join(task.Namespaces, ",")
-		newTaskMap[key] = task
 
 		if _, exists := c.taskRoutines[key]; !exists {
+			ctx, cancel := context.WithCancel(c.ctx)
+			routine := taskRoutine{stopch: make(chan struct{}), cancel: cancel}
+			c.taskRoutines[key] = routine
 
-			stopch := make(chan struct{})
-			c.taskRoutines[key] = stopch
-
-			go c.runTasks(task, stopch)
+			c.routines.Add(1)
+			go c.runTasks(ctx, task, routine)
 		}
 	}
 }
 
-func (c *Scheduler) runTasks(task SchedulerTask, stopch chan struct{}) {
+func (c *Scheduler) runTasks(ctx context.Context, task SchedulerTask, routine taskRoutine) {
 	slog.Info("task", "provided namespace(s)", task.Namespaces, "period time", task.WithCron,
 		"recurrence", task.Recurrence, "status", "initializing",
 	)
 
 	defer func() {
+		routine.cancel()
+		c.routines.Done()
+		c.Health.TaskStopped(task.Name())
 		slog.Info("task", "provided namespace(s)", task.Namespaces, "period time", task.WithCron,
 			"recurrence", task.Recurrence, "status", "terminated",
 		)
 	}()
 
 	var (
+		stopch         = routine.stopch
 		namespaces     = task.Namespaces
 		recurrence     = task.Recurrence
 		recurrenceDays = parseRecurrence(recurrence)
@@ -182,6 +362,7 @@ func (c *Scheduler) runTasks(task SchedulerTask, stopch chan struct{}) {
 		namespaces = unspecified.ReplaceSpecialFlagWithNamespaces(clusterNamespaces, namespaces)
 	}
 
+	reconciled := false
 	for {
 		select {
 		case <-stopch:
@@ -190,30 +371,56 @@ func (c *Scheduler) runTasks(task SchedulerTask, stopch chan struct{}) {
 			targetTimeToUpscale, targetTimeToDownscale := extractUpscalingAndDownscalingTime(task.WithCron, c.Location)
 			now := c.now()
 
+			c.Health.Heartbeat(task.Name(), 0)
+			c.retryFailedScaling(task, namespaces, now, targetTimeToUpscale, targetTimeToDownscale)
+
+			regular := c.handleOverrides(task, namespaces, now, targetTimeToUpscale, targetTimeToDownscale)
+			if len(regular) == 0 {
+				logWaitOverriddenNamespacesWithSleep(stopch, namespaces)
+				continue
+			}
+
 			if !c.isRecurrenceDay(now.Weekday(), recurrenceDays) {
-				logWaitRecurrenceDaysWithSleep(now.Weekday())
+				logWaitRecurrenceDaysWithSleep(stopch, now.Weekday())
 				continue
 			}
 
-			if valid := c.validateSchedulerNamespaces(c.ctx, namespaces); !valid {
+			if valid := c.validateSchedulerNamespaces(c.ctx, stopch, regular); !valid {
 				continue
 			}
 
-			currentReplicasState, err := c.inspectReplicasStateByNamespace(c.ctx, namespaces)
+			currentReplicasState, err := c.inspectReplicasStateByNamespace(c.ctx, regular)
 			if err != nil && currentReplicasState == shared.InspectError {
 				slog.Error("inspect replicas by namespace error", "err", err)
 				continue
 			}
 
+			if !reconciled || currentReplicasState == shared.DeploymentsWithMixedState {
+				reconciled = true
+				scheduledDown := scheduledDownByTime(now, targetTimeToUpscale, targetTimeToDownscale, recurrenceDays)
+				if c.reconcileNamespaces(task, regular, scheduledDown) {
+					continue
+				}
+			}
+
+			if currentReplicasState == shared.DeploymentsWithMixedState {
+				c.reportNamespacePhases(task, regular, status.PhaseTransitioning)
+				logWaitMixedStateWithSleep(stopch, regular)
+				continue
+			}
+
+			c.reportRuleSchedule(task, namespaces, now, recurrenceDays, currentReplicasState == shared.DeploymentsWithDownscaledState)
+			c.reportNamespacePhases(task, regular, phaseFromReplicasState(currentReplicasState))
+
 			if validateIfShouldRunDownscalingOrWait(now, currentReplicasState, targetTimeToDownscale, targetTimeToUpscale) {
-				logWaitBeforeDownscalingWithSleep(now, task.WithCron, namespaces)
+				logWaitBeforeDownscalingWithSleep(stopch, now, task.WithCron, regular)
 				continue
 			}
 
 			if currentReplicasState == shared.DeploymentsWithUpscaledState || currentReplicasState == shared.AppStartupWithNoDataWrite || currentReplicasState == shared.UpscalingDeactivated {
-				c.handleDownscaling(task, namespaces)
+				c.handleDownscaling(task, regular)
 
-				next := c.handleUpscaling(task, stopch, namespaces)
+				next := c.handleUpscaling(ctx, task, stopch, namespaces)
 				if next == shared.KillCurrentRoutine {
 					return
 				}
@@ -224,7 +431,7 @@ func (c *Scheduler) runTasks(task SchedulerTask, stopch chan struct{}) {
 			}
 
 			if currentReplicasState == shared.DeploymentsWithDownscaledState {
-				next := c.handleUpscaling(task, stopch, namespaces)
+				next := c.handleUpscaling(ctx, task, stopch, namespaces)
 				if next == shared.KillCurrentRoutine {
 					return
 				}
@@ -238,10 +445,19 @@ func (c *Scheduler) runTasks(task SchedulerTask, stopch chan struct{}) {
 }
 
 func (c *Scheduler) killCurrentSchedulerRoutines() {
-	if len(c.taskRoutines) > 0 {
-		for key, stopch := range c.taskRoutines {
-			delete(c.taskRoutines, key)
-			close(stopch)
+	c.stopCurrentSchedulerRoutines(true)
+}
+
+// a drain stops the routines without cancelling them so the in-flight scaling can finish
+func (c *Scheduler) stopCurrentSchedulerRoutines(cancel bool) {
+	c.routinesMu.Lock()
+	defer c.routinesMu.Unlock()
+
+	for key, routine := range c.taskRoutines {
+		delete(c.taskRoutines, key)
+		close(routine.stopch)
+		if cancel {
+			routine.cancel()
 		}
 	}
 }
@@ -273,111 +489,109 @@ func (c *Scheduler) updateRecurrenceIfEmpty(recurrence string) {
 
 func (c *Scheduler) inspectReplicasStateByNamespace(ctx context.Context, namespaces []string) (shared.TaskControl, error) {
 	if c.input.RunUpscaling {
-		namespaceState := make(map[string]shared.Apps)
-		cm := c.Kubernetes.ListConfigMap(ctx, c.input.ConfigMapName, c.input.ConfigMapNamespace)
-
-		err := common.UnmarshalDataPolicy(cm, namespaceState)
-		if err != nil {
-			slog.Error("error unmarshaling data time policy", "error", err)
-			return -1, err
-		}
-
 		var (
-			replicaCountSum int = 0
-			notEmptyIndex   int = 0
+			downscaledWorkloads int = 0
+			totalWorkloads      int = 0
 		)
 
-		if !namespaceIndexAvailable(namespaces, cm) {
-			return shared.AppStartupWithNoDataWrite, nil
-		}
-
 		for _, namespace := range namespaces {
-			if metadata, found := namespaceState[namespace+".yaml"]; found {
-				if metadata.State == nil && metadata.Status == shared.EmptyNamespace {
-					continue
-				}
-				sum, count, err := parseReplicaState(metadata.State)
-				if err != nil {
-					slog.Error("conversion error", "err", err)
-					return shared.InspectError, err
-				}
-				replicaCountSum += sum
-				notEmptyIndex += count
+			namespaceState, err := c.State.Get(ctx, namespace)
+			if errors.Is(err, state.ErrNotFound) {
+				return shared.AppStartupWithNoDataWrite, nil
+			}
+			if err != nil {
+				slog.Error("error reading the state", "namespace", namespace, "error", err)
+				return shared.InspectError, err
 			}
-		}
 
-		if replicaCountSum/notEmptyIndex == int(shared.DeploymentsWithDownscaledState) {
-			return shared.DeploymentsWithDownscaledState, nil
+			metadata := namespaceState.Apps
+			metrics.NamespaceState(namespace, metadata)
+			if metadata.State == nil && metadata.Status == shared.EmptyNamespace {
+				continue
+			}
+			downscaled, total := countDownscaledWorkloads(metadata.State)
+			downscaledWorkloads += downscaled
+			totalWorkloads += total
 		}
 
-		if replicaCountSum/notEmptyIndex == int(shared.DeploymentsWithUpscaledState) {
+		switch downscaledWorkloads {
+		case 0:
 			return shared.DeploymentsWithUpscaledState, nil
+		case totalWorkloads:
+			return shared.DeploymentsWithDownscaledState, nil
+		default:
+			return shared.DeploymentsWithMixedState, nil
 		}
 	}
 	return shared.UpscalingDeactivated, nil
 }
 
-func (c *Scheduler) checkIfNamespaceExistsInConfigMapBeforeWrite(ctx context.Context, cmCurrentState map[string]shared.Apps, cmData map[string]string) error {
-	for namespace := range cmCurrentState {
-		namespaceYamlKey := getNamespaceWithYamlExt(namespace)
-		if _, exists := cmData[namespaceYamlKey]; !exists {
-			patchWithKey, err := createConfigMapKeyForPatching(namespaceYamlKey)
-			if err != nil {
-				slog.Error("error creating configmap key for patching", "error", err)
-				return err
+func (c *Scheduler) readStateByNamespace(ctx context.Context, namespaces []string) map[string]*state.NamespaceState {
+	stateByNamespace := make(map[string]*state.NamespaceState, len(namespaces))
+	for _, namespace := range namespaces {
+		namespaceState, err := c.State.Get(ctx, namespace)
+		if err != nil {
+			if !errors.Is(err, state.ErrNotFound) {
+				slog.Error("error reading the state", "namespace", namespace, "error", err)
 			}
-			c.Kubernetes.PatchConfigMap(ctx, c.input.ConfigMapName, c.input.ConfigMapNamespace, patchWithKey)
+			continue
 		}
+		stateByNamespace[namespace] = namespaceState
 	}
-	return nil
+	return stateByNamespace
 }
 
-func (c *Scheduler) writeCmValueByNamespaceKey(ctx context.Context, cmCurrentState map[string]shared.Apps) error {
-	for namespace := range cmCurrentState {
-		var (
-			namespaceKey   = getNamespaceWithYamlExt(namespace)
-			metadataFromCm = cmCurrentState[namespace]
-		)
-
-		patchWith := shared.Apps{
-			Status: metadataFromCm.Status,
-			Group:  metadataFromCm.Group,
-			State:  extractSegments(metadataFromCm),
+func (c *Scheduler) writeStateByNamespace(ctx context.Context, stateByNamespace map[string]shared.Apps, readVersions map[string]*state.NamespaceState) error {
+	for namespace, apps := range stateByNamespace {
+		namespaceState := &state.NamespaceState{Namespace: namespace, Apps: apps}
+		if previous, found := readVersions[namespace]; found {
+			namespaceState.ResourceVersion = previous.ResourceVersion
 		}
+		namespaceState.Apps.Unparsed = c.unparsedState(ctx, namespace, apps, readVersions)
 
-		yamlData, err := yaml.Marshal(patchWith)
-		if err != nil {
-			return err
+		err := c.State.Put(ctx, namespaceState)
+		if errors.Is(err, state.ErrConflict) {
+			slog.Warn("state", "namespace", namespace, "status", "conflict", "action", "writing by workload")
+			err = c.writeStateByWorkload(ctx, namespace, apps)
 		}
-		patch := &corev1.ConfigMap{
-			Data: map[string]string{
-				namespaceKey: string(yamlData),
-			},
-		}
-		patchBytes, err := json.Marshal(patch)
 		if err != nil {
 			return err
 		}
-		c.Kubernetes.PatchConfigMap(ctx, c.input.ConfigMapName, c.input.ConfigMapNamespace, patchBytes)
 	}
 	return nil
 }
 
-func (c *Scheduler) writeOldStateDeploymentsReplicas(ctx context.Context, cmCurrentState map[string]shared.Apps) error {
-	if c.input.RunUpscaling {
-		currentCm := c.Kubernetes.ListConfigMap(ctx, c.input.ConfigMapName, c.input.ConfigMapNamespace)
-		err := c.checkIfNamespaceExistsInConfigMapBeforeWrite(ctx, cmCurrentState, currentCm.Data)
-		if err != nil {
-			return err
-		}
+// legacy entries that could not be parsed are only dropped by fixing or removing them by hand
+func (c *Scheduler) unparsedState(ctx context.Context, namespace string, apps shared.Apps, readVersions map[string]*state.NamespaceState) []string {
+	if len(apps.Unparsed) > 0 {
+		return apps.Unparsed
+	}
+	if previous, found := readVersions[namespace]; found {
+		return previous.Apps.Unparsed
+	}
+	previous, err := c.State.Get(ctx, namespace)
+	if err != nil {
+		return nil
+	}
+	return previous.Apps.Unparsed
+}
 
-		if err := c.writeCmValueByNamespaceKey(ctx, cmCurrentState); err != nil {
+func (c *Scheduler) writeStateByWorkload(ctx context.Context, namespace string, apps shared.Apps) error {
+	for _, workload := range apps.State {
+		if err := c.State.PutWorkload(ctx, namespace, workload, ""); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (c *Scheduler) writeOldStateDeploymentsReplicas(ctx context.Context, cmCurrentState map[string]shared.Apps) error {
+	if c.input.RunUpscaling {
+		return c.writeStateByNamespace(ctx, cmCurrentState, nil)
+	}
+	return nil
+}
+
 func (c *Scheduler) updateTimeZoneIfNotEqual(timezone string) error {
 	if !strings.EqualFold(c.Location.String(), timezone) {
 		location, err := time.LoadLocation(timezone)
@@ -401,17 +615,27 @@ func (c *Scheduler) handleDownscaling(task SchedulerTask, namespaces []string) {
 		ScheduledNamespaces: task.ScheduledNamespaces,
 	}
 
-	toCmCurrentState := c.Kubernetes.StartDownscaling(c.ctx, namespaces, notUsableNamespaces)
+	c.reportNamespacePhases(task, namespaces, status.PhaseTransitioning)
+	c.Health.Heartbeat(task.Name(), 0)
+
+	toCmCurrentState := c.Kubernetes.StartDownscaling(c.ctx, namespaces, notUsableNamespaces, task.Name())
 
 	c.releaseTaskRoutineIfNotUpscaling(task)
+	metrics.NamespaceStates(toCmCurrentState)
 
-	err := c.writeOldStateDeploymentsReplicas(c.ctx, toCmCurrentState)
+	ctx, cancel := c.persistContext()
+	defer cancel()
+
+	err := c.writeOldStateDeploymentsReplicas(ctx, toCmCurrentState)
 	if err != nil {
 		slog.Error("error writing state after downscaling", "err", err)
+		c.reportNamespaceError(task, namespaces, err)
+		return
 	}
+	c.reportNamespacePhases(task, namespaces, status.PhaseDown)
 }
 
-func (c *Scheduler) handleUpscaling(task SchedulerTask, stopch chan struct{}, namespaces []string) shared.TaskControl {
+func (c *Scheduler) handleUpscaling(ctx context.Context, task SchedulerTask, stopch chan struct{}, namespaces []string) shared.TaskControl {
 	for {
 		select {
 		case <-stopch:
@@ -420,26 +644,62 @@ func (c *Scheduler) handleUpscaling(task SchedulerTask, stopch chan struct{}, na
 			now := c.now()
 			targetTimeToUpscale, targetTimeToDownscale := extractUpscalingAndDownscalingTime(task.WithCron, c.Location)
 
-			response, err := c.inspectReplicasStateByNamespace(c.ctx, namespaces)
+			c.Health.Heartbeat(task.Name(), 0)
+			c.retryFailedScaling(task, namespaces, now, targetTimeToUpscale, targetTimeToDownscale)
+
+			regular := c.handleOverrides(task, namespaces, now, targetTimeToUpscale, targetTimeToDownscale)
+			if len(regular) == 0 {
+				logWaitOverriddenNamespacesWithSleep(stopch, namespaces)
+				continue
+			}
+
+			response, err := c.inspectReplicasStateByNamespace(c.ctx, regular)
 			if err != nil && response == shared.InspectError {
 				slog.Error("inspect replicas by namespace error", "err", err)
 				continue
 			}
 
+			if response == shared.DeploymentsWithMixedState {
+				c.reconcileNamespaces(task, regular, scheduledDownByTime(now, targetTimeToUpscale, targetTimeToDownscale, parseRecurrence(task.Recurrence)))
+				return shared.RestartRoutine
+			}
+
+			c.reportRuleSchedule(task, namespaces, now, parseRecurrence(task.Recurrence), response == shared.DeploymentsWithDownscaledState)
+			c.reportNamespacePhases(task, regular, phaseFromReplicasState(response))
+
 			if validateIfShoudRunUpscalingOrWait(now, targetTimeToUpscale, targetTimeToDownscale) {
-				logWaitAfterDownscalingWithSleep(now, task.WithCron, namespaces)
+				if response == shared.DeploymentsWithDownscaledState {
+					c.trackWorkloadChanges(task, regular)
+					c.enforceDownWindow(task, regular)
+				}
+				logWaitAfterDownscalingWithSleep(stopch, now, task.WithCron, regular)
 				continue
 			}
 
 			if response == shared.DeploymentsWithDownscaledState {
-				cmAppsSlice := c.Kubernetes.StartUpscaling(c.ctx, task.ScheduledNamespaces, namespaces, c.input.ConfigMapName, c.input.ConfigMapNamespace)
-				for _, cmApps := range cmAppsSlice {
-					if err := c.writeCmValueByNamespaceKey(c.ctx, cmApps); err != nil {
-						slog.Error("error writing state after upscaling", "err", err)
-					}
+				stateByNamespace := c.readStateByNamespace(c.ctx, regular)
+
+				appsByNamespace := make(map[string]shared.Apps, len(stateByNamespace))
+				for namespace, namespaceState := range stateByNamespace {
+					appsByNamespace[namespace] = namespaceState.Apps
 				}
-				return shared.RestartRoutine
+
+				c.reportNamespacePhases(task, regular, status.PhaseTransitioning)
+				c.Health.Heartbeat(task.Name(), task.UpscaleSpread.Duration)
+
+				upscaledState := c.Kubernetes.StartUpscaling(ctx, appsByNamespace, regular, task.UpscaleSpread, task.Name())
+				metrics.NamespaceStates(upscaledState)
+				persistCtx, cancel := c.persistContext()
+				err := c.writeStateByNamespace(persistCtx, upscaledState, stateByNamespace)
+				cancel()
+				if err != nil {
+					slog.Error("error writing state after upscaling", "err", err)
+					c.reportNamespaceError(task, regular, err)
+					return shared.RestartRoutine
+				}
+				c.reportNamespacePhases(task, regular, status.PhaseUp)
 			}
+			return shared.RestartRoutine
 		}
 	}
 }
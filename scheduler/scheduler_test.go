@@ -1,10 +1,20 @@
 package scheduler
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/adalbertjnr/downscaler/events"
+	"github.com/adalbertjnr/downscaler/input"
+	"github.com/adalbertjnr/downscaler/kas"
 	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestBeforeDownscalingValidation(t *testing.T) {
@@ -68,3 +78,340 @@ func TestAfterDownscalingValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestNextTransitions(t *testing.T) {
+	var (
+		location        = time.UTC
+		downscalingTime = time.Date(2024, time.June, 3, 22, 0, 0, 0, location)
+		upscalingTime   = time.Date(2024, time.June, 3, 6, 0, 0, 0, location)
+		weekdays        = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	)
+
+	tests := []struct {
+		name              string
+		now               time.Time
+		downscaled        bool
+		expectedDownscale time.Time
+		expectedUpscale   time.Time
+	}{
+		{"Upscaled on monday before downscaling time", time.Date(2024, time.June, 3, 12, 0, 0, 0, location), false, time.Date(2024, time.June, 3, 22, 0, 0, 0, location), time.Date(2024, time.June, 4, 6, 0, 0, 0, location)},
+		{"Downscaled on monday after downscaling time", time.Date(2024, time.June, 3, 23, 0, 0, 0, location), true, time.Date(2024, time.June, 4, 22, 0, 0, 0, location), time.Date(2024, time.June, 4, 6, 0, 0, 0, location)},
+		{"Upscaled on friday after downscaling time skips the weekend", time.Date(2024, time.June, 7, 23, 0, 0, 0, location), false, time.Date(2024, time.June, 10, 22, 0, 0, 0, location), time.Date(2024, time.June, 11, 6, 0, 0, 0, location)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextDownscale, nextUpscale := nextTransitions(tt.now, downscalingTime, upscalingTime, weekdays, tt.downscaled)
+			if !nextDownscale.Equal(tt.expectedDownscale) || !nextUpscale.Equal(tt.expectedUpscale) {
+				t.Errorf("nextTransitions(%v) = (%v, %v); expected (%v, %v)", tt.now, nextDownscale, nextUpscale, tt.expectedDownscale, tt.expectedUpscale)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	policy := &shared.DownscalerPolicy{}
+	policy.Spec.ExecutionOpts.Time.TimeZone = "UTC"
+	policy.Spec.ExecutionOpts.Time.Recurrence = "MON-FRI"
+	policy.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Values = []string{"kube-system"}
+	policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = []shared.Rule{
+		{Namespaces: []string{"nginx-2", "kube-system"}, WithCron: "06:00-22:00"},
+	}
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		expected []PlannedTransition
+	}{
+		{"Friday night until monday morning", time.Date(2024, time.June, 7, 12, 0, 0, 0, time.UTC), time.Date(2024, time.June, 10, 12, 0, 0, 0, time.UTC), []PlannedTransition{
+			{Time: time.Date(2024, time.June, 7, 22, 0, 0, 0, time.UTC), Transition: TransitionDownscale, Namespace: "nginx-2", Rule: "nginx-2,kube-system 06:00-22:00"},
+			{Time: time.Date(2024, time.June, 8, 6, 0, 0, 0, time.UTC), Transition: TransitionUpscale, Namespace: "nginx-2", Rule: "nginx-2,kube-system 06:00-22:00"},
+		}},
+		{"Weekend without transitions", time.Date(2024, time.June, 8, 12, 0, 0, 0, time.UTC), time.Date(2024, time.June, 9, 23, 0, 0, 0, time.UTC), []PlannedTransition{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transitions, err := Plan(policy, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if len(transitions) != len(tt.expected) {
+				t.Fatalf("Plan() = %+v; expected %+v", transitions, tt.expected)
+			}
+			for i := range transitions {
+				if !transitions[i].Time.Equal(tt.expected[i].Time) || transitions[i].Transition != tt.expected[i].Transition || transitions[i].Namespace != tt.expected[i].Namespace || transitions[i].Rule != tt.expected[i].Rule {
+					t.Errorf("Plan()[%d] = %+v; expected %+v", i, transitions[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileWorkload(t *testing.T) {
+	var (
+		now        = time.Date(2024, time.June, 3, 23, 0, 0, 0, time.UTC)
+		downscaled = shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}
+		upscaled   = shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseUpscaled}
+	)
+
+	tests := []struct {
+		name             string
+		workload         shared.Workload
+		liveReplicas     int32
+		scheduledDown    bool
+		expectedScale    bool
+		expectedReplicas int32
+		expectedPhase    shared.Phase
+		expectedOriginal int32
+	}{
+		{"Downscaled and down as scheduled", downscaled, 0, true, false, 0, shared.PhaseDownscaled, 3},
+		{"Recorded as downscaled but still running during the down window", downscaled, 3, true, true, 0, shared.PhaseDownscaled, 3},
+		{"Left running by an interrupted downscaling", upscaled, 5, true, true, 0, shared.PhaseDownscaled, 5},
+		{"Already at zero replicas during the down window", upscaled, 0, true, false, 0, shared.PhaseUpscaled, 3},
+		{"Left down by an interrupted upscaling", downscaled, 0, false, true, 3, shared.PhaseUpscaled, 3},
+		{"Upscaled before the state was written", downscaled, 3, false, false, 3, shared.PhaseUpscaled, 3},
+		{"Upscaled and up as scheduled", upscaled, 3, false, false, 0, shared.PhaseUpscaled, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := reconcileWorkload(tt.workload, tt.liveReplicas, tt.scheduledDown, "nginx-2 01:30-14:50", now)
+			if decision.scale != tt.expectedScale || (decision.scale && decision.replicas != tt.expectedReplicas) {
+				t.Errorf("reconcileWorkload() scale = (%v, %d); expected (%v, %d)", decision.scale, decision.replicas, tt.expectedScale, tt.expectedReplicas)
+			}
+			if decision.workload.Phase != tt.expectedPhase || decision.workload.OriginalReplicas != tt.expectedOriginal {
+				t.Errorf("reconcileWorkload() workload = (%s, %d); expected (%s, %d)", decision.workload.Phase, decision.workload.OriginalReplicas, tt.expectedPhase, tt.expectedOriginal)
+			}
+		})
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	downscaled := shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled, TemplateHash: "a1"}
+
+	tests := []struct {
+		name         string
+		workload     shared.Workload
+		replicas     int32
+		templateHash string
+		expected     drift
+	}{
+		{"Still downscaled", downscaled, 0, "a1", driftNone},
+		{"Scaled up by hand", downscaled, 3, "a1", driftScaled},
+		{"Redeployed with new replicas", downscaled, 5, "b2", driftRedeployed},
+		{"Scaled up without a recorded template", shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}, 2, "b2", driftScaled},
+		{"Upscaled workload", shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseUpscaled}, 3, "b2", driftNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if detected := detectDrift(tt.workload, tt.replicas, tt.templateHash); detected != tt.expected {
+				t.Errorf("detectDrift() = %d; expected %d", detected, tt.expected)
+			}
+		})
+	}
+}
+
+type driftedKubernetes struct {
+	kas.Kubernetes
+	deployments []v1.Deployment
+	scaled      int
+}
+
+func (k *driftedKubernetes) GetDeployments(context.Context, string) (*v1.DeploymentList, error) {
+	return &v1.DeploymentList{Items: k.deployments}, nil
+}
+
+func (k *driftedKubernetes) ScaleDeployments(context.Context, string, *v1.Deployment, []byte, int32, string) error {
+	k.scaled++
+	return nil
+}
+
+func TestEnforceCorrectsADriftOnce(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		replicas = int32(3)
+		drifted  = v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "nginx-1", ResourceVersion: "10"}, Spec: v1.DeploymentSpec{Replicas: &replicas}}
+		k        = &driftedKubernetes{deployments: []v1.Deployment{drifted}}
+		recorder = record.NewFakeRecorder(10)
+		store    = state.NewMemoryStore()
+	)
+
+	err := store.Put(ctx, &state.NamespaceState{Namespace: "nginx-1", Apps: shared.Apps{State: []shared.Workload{{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}}}})
+	if err != nil {
+		t.Fatalf("Put(nginx-1) error = %v", err)
+	}
+
+	scheduler := NewScheduler().AddKubeApiSvc(k).AddStateStore(store).AddEventRecorder(events.NewRecorderFrom(recorder)).AddInput(&input.FromArgs{Enforce: true, RunUpscaling: true})
+	task := SchedulerTask{Rules: Rules{Namespaces: []string{"nginx-1"}, WithCron: "01:30-14:50"}}
+
+	scheduler.enforceDownWindow(task, []string{"nginx-1"})
+	scheduler.enforceDownWindow(task, []string{"nginx-1"})
+	if len(recorder.Events) != 1 || k.scaled != 1 {
+		t.Errorf("events = %d, scales = %d while the informer lags; expected one correction", len(recorder.Events), k.scaled)
+	}
+
+	k.deployments[0].ResourceVersion = "12"
+	scheduler.enforceDownWindow(task, []string{"nginx-1"})
+	if len(recorder.Events) != 2 || k.scaled != 2 {
+		t.Errorf("events = %d, scales = %d after a new drift; expected two corrections", len(recorder.Events), k.scaled)
+	}
+}
+
+func TestDiffWorkloads(t *testing.T) {
+	replicas, zero := int32(2), int32(0)
+	apps := shared.Apps{State: []shared.Workload{
+		{Name: "api", UID: "api-1", OriginalReplicas: 3, Phase: shared.PhaseDownscaled},
+		{Name: "web", OriginalReplicas: 1, Phase: shared.PhaseDownscaled},
+	}}
+	deployment := func(name string, replicas *int32) v1.Deployment {
+		return v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-1")}, Spec: v1.DeploymentSpec{Replicas: replicas}}
+	}
+	recreated := deployment("api", &replicas)
+	recreated.UID = "api-2"
+
+	tests := []struct {
+		name            string
+		deployments     []v1.Deployment
+		expectedAdopted []string
+		expectedPruned  []string
+	}{
+		{"Nothing changed", []v1.Deployment{deployment("api", &zero), deployment("web", &zero)}, nil, nil},
+		{"Created while downscaled", []v1.Deployment{deployment("api", &zero), deployment("web", &zero), deployment("worker", &replicas)}, []string{"worker"}, nil},
+		{"Created with zero replicas", []v1.Deployment{deployment("api", &zero), deployment("web", &zero), deployment("worker", &zero)}, nil, nil},
+		{"Deleted while downscaled", []v1.Deployment{deployment("api", &zero)}, nil, []string{"web"}},
+		{"Every deployment deleted while downscaled", nil, nil, []string{"api", "web"}},
+		{"Recreated while downscaled", []v1.Deployment{recreated, deployment("web", &zero)}, []string{"api"}, []string{"api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffWorkloads(apps, tt.deployments)
+
+			adopted := make([]string, len(changes.adopted))
+			for i, deployment := range changes.adopted {
+				adopted[i] = deployment.Name
+			}
+			pruned := make([]string, len(changes.pruned))
+			for i, workload := range changes.pruned {
+				pruned[i] = workload.Name
+			}
+
+			if strings.Join(adopted, ",") != strings.Join(tt.expectedAdopted, ",") || strings.Join(pruned, ",") != strings.Join(tt.expectedPruned, ",") {
+				t.Errorf("diffWorkloads() = (%v, %v); expected (%v, %v)", adopted, pruned, tt.expectedAdopted, tt.expectedPruned)
+			}
+		})
+	}
+}
+
+func TestResolveOverlaps(t *testing.T) {
+	rules := []shared.Rule{
+		{Namespaces: []string{"nginx-1", "nginx-2"}, WithCron: "08:00-20:00"},
+		{Namespaces: []string{"nginx-2"}, WithCron: "10:00-18:00"},
+		{Namespaces: []string{"nginx-2", "nginx-3"}, WithCron: "06:00-11:00PM"},
+	}
+
+	tests := []struct {
+		name           string
+		resolution     string
+		expectedRules  []string
+		expectedWinner string
+	}{
+		{"First rule wins", shared.OverlapFirstRule, []string{"nginx-1,nginx-2 08:00-20:00", "nginx-3 06:00-11:00PM"}, "08:00-20:00"},
+		{"Most restrictive wins", shared.OverlapMostRestrictive, []string{"nginx-1 08:00-20:00", "nginx-2 10:00-18:00", "nginx-3 06:00-11:00PM"}, "10:00-18:00"},
+		{"Longest uptime wins", shared.OverlapLongestUptime, []string{"nginx-1 08:00-20:00", "nginx-2,nginx-3 06:00-11:00PM"}, "06:00-11:00PM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, overlaps := resolveOverlaps(shared.DownscalerRules{Rules: rules, OverlapResolution: tt.resolution})
+
+			names := make([]string, len(resolved.Rules))
+			for i, rule := range resolved.Rules {
+				names[i] = SchedulerTask{Rules: Rules{Namespaces: rule.Namespaces, WithCron: rule.WithCron}}.Name()
+			}
+			if strings.Join(names, "|") != strings.Join(tt.expectedRules, "|") {
+				t.Errorf("resolved rules = %v; expected %v", names, tt.expectedRules)
+			}
+			if len(overlaps) != 1 || overlaps[0].Namespace != "nginx-2" || overlaps[0].Winner != tt.expectedWinner || len(overlaps[0].Rules) != 3 {
+				t.Errorf("overlaps = %+v; expected nginx-2 won by %s", overlaps, tt.expectedWinner)
+			}
+		})
+	}
+}
+
+func TestStopCurrentSchedulerRoutines(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelled bool
+	}{
+		{"Killed on a policy change", true},
+		{"Stopped on a drain", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewScheduler()
+			ctx, cancel := context.WithCancel(c.ctx)
+			defer cancel()
+			routine := taskRoutine{stopch: make(chan struct{}), cancel: cancel}
+			c.taskRoutines["08:00-20:00nginx-1"] = routine
+
+			c.stopCurrentSchedulerRoutines(tt.cancelled)
+
+			if len(c.taskRoutines) != 0 {
+				t.Errorf("len(taskRoutines) = %d; expected 0", len(c.taskRoutines))
+			}
+			select {
+			case <-routine.stopch:
+			default:
+				t.Errorf("stopch is open; expected the routine to be stopped")
+			}
+			if cancelled := ctx.Err() != nil; cancelled != tt.cancelled {
+				t.Errorf("task context cancelled = %v; expected %v", cancelled, tt.cancelled)
+			}
+		})
+	}
+}
+
+func TestDropStaleRetries(t *testing.T) {
+	var (
+		targetTimeToUpscale   = time.Date(2024, time.June, 3, 8, 0, 0, 0, time.UTC)
+		targetTimeToDownscale = time.Date(2024, time.June, 3, 20, 0, 0, 0, time.UTC)
+		recurrenceDays        = parseRecurrence("MON-SUN")
+		failedDownscaling     = shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseUpscaled, LastError: "etcdserver: request timed out"}
+		failedUpscaling       = shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled, LastError: "etcdserver: request timed out"}
+	)
+
+	tests := []struct {
+		name            string
+		workload        shared.Workload
+		now             time.Time
+		expectedDropped bool
+	}{
+		{"Failed downscaling retried in the down window", failedDownscaling, time.Date(2024, time.June, 3, 23, 0, 0, 0, time.UTC), false},
+		{"Failed downscaling once the clock moves into the up window", failedDownscaling, time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC), true},
+		{"Failed upscaling retried in the up window", failedUpscaling, time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC), false},
+		{"Failed upscaling once the clock moves into the down window", failedUpscaling, time.Date(2024, time.June, 3, 23, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apps := shared.Apps{State: []shared.Workload{tt.workload}}
+			scheduledDown := scheduledDownByTime(tt.now, targetTimeToUpscale, targetTimeToDownscale, recurrenceDays)
+
+			updated, dropped := dropStaleRetries("nginx-1", apps, scheduledDown)
+			if dropped != tt.expectedDropped {
+				t.Errorf("dropped = %v; expected %v", dropped, tt.expectedDropped)
+			}
+			if updated.State[0].Failed() == tt.expectedDropped {
+				t.Errorf("Failed() = %v after dropping = %v; expected the retry to be pending only when kept", updated.State[0].Failed(), dropped)
+			}
+			if updated.State[0].Phase != tt.workload.Phase || !apps.State[0].Failed() {
+				t.Errorf("State = %+v; expected the phase kept and the read state untouched", updated.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/status"
+)
+
+func (c *Scheduler) AddStatusReporter(reporter *status.Reporter) *Scheduler {
+	c.Status = reporter
+	return c
+}
+
+func rulesByName(rules shared.DownscalerRules) map[string][]string {
+	byName := make(map[string][]string, len(rules.Rules))
+	for _, rule := range rules.Rules {
+		task := SchedulerTask{Rules: Rules{Namespaces: rule.Namespaces, WithCron: rule.WithCron}}
+		byName[task.Name()] = rule.Namespaces
+	}
+	return byName
+}
+
+func (c *Scheduler) reportRuleSchedule(task SchedulerTask, namespaces []string, now time.Time, recurrenceDays []time.Weekday, downscaled bool) {
+	targetTimeToUpscale, targetTimeToDownscale := extractUpscalingAndDownscalingTime(task.WithCron, c.Location)
+	nextDownscale, nextUpscale := nextTransitions(now, targetTimeToDownscale, targetTimeToUpscale, recurrenceDays, downscaled)
+	c.Status.RuleSchedule(task.Name(), namespaces, nextDownscale, nextUpscale)
+	metrics.RuleSchedule(c.policyName, task.Name(), nextDownscale, nextUpscale)
+}
+
+func (c *Scheduler) reportNamespacePhases(task SchedulerTask, namespaces []string, phase status.NamespacePhase) {
+	if c.Status == nil {
+		return
+	}
+
+	failed, unparsed := c.stateFailures(namespaces)
+	for _, namespace := range namespaces {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored {
+			continue
+		}
+		c.Status.UnparsedState(namespace, unparsed[namespace])
+		if deployments, found := failed[namespace]; found {
+			sort.Strings(deployments)
+			c.Status.NamespacePhase(namespace, task.Name(), status.PhaseError, "scaling failed for deployment(s) "+strings.Join(deployments, ", ")+", retrying")
+			continue
+		}
+		c.Status.NamespacePhase(namespace, task.Name(), phase, "")
+	}
+}
+
+func (c *Scheduler) reportNamespaceError(task SchedulerTask, namespaces []string, err error) {
+	for _, namespace := range namespaces {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored {
+			continue
+		}
+		c.Status.NamespacePhase(namespace, task.Name(), status.PhaseError, err.Error())
+	}
+}
+
+func phaseFromReplicasState(currentReplicasState shared.TaskControl) status.NamespacePhase {
+	if currentReplicasState == shared.DeploymentsWithDownscaledState {
+		return status.PhaseDown
+	}
+	return status.PhaseUp
+}
+
+func nextTransitions(now, targetTimeToDownscale, targetTimeToUpscale time.Time, recurrenceDays []time.Weekday, downscaled bool) (nextDownscale, nextUpscale time.Time) {
+	nextDownscale = nextRecurrenceTime(now, targetTimeToDownscale, recurrenceDays)
+
+	from := nextDownscale
+	if downscaled {
+		from = now
+	}
+	nextUpscale = targetTimeToUpscale
+	for !nextUpscale.After(from) {
+		nextUpscale = nextUpscale.AddDate(0, 0, 1)
+	}
+
+	return nextDownscale, nextUpscale
+}
+
+func nextRecurrenceTime(now, target time.Time, recurrenceDays []time.Weekday) time.Time {
+	next := target
+	for day := 0; day < 8; day++ {
+		if next.After(now) && isWeekdayIn(next.Weekday(), recurrenceDays) {
+			return next
+		}
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func isWeekdayIn(day time.Weekday, recurrenceDays []time.Weekday) bool {
+	for _, recurrenceDay := range recurrenceDays {
+		if day == recurrenceDay {
+			return true
+		}
+	}
+	return false
+}
@@ -1,84 +1,39 @@
 package scheduler
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/adalbertjnr/downscaler/shared"
-	corev1 "k8s.io/api/core/v1"
 )
 
 func (c *Scheduler) releaseTaskRoutineIfNotUpscaling(task SchedulerTask) {
 	if !c.input.RunUpscaling {
 		key := task.WithCron + strings.Join(task.Namespaces, ",")
-		if ch, exists := c.taskRoutines[key]; exists {
+
+		c.routinesMu.Lock()
+		defer c.routinesMu.Unlock()
+		if routine, exists := c.taskRoutines[key]; exists {
 			delete(c.taskRoutines, key)
-			close(ch)
+			close(routine.stopch)
 		}
 	}
 }
 
-func namespaceIndexAvailable(namespaces []string, cm *corev1.ConfigMap) bool {
-	if cm.Data == nil {
-		return false
-	}
-
-	for _, taskNamespace := range namespaces {
-		if cm.Data[taskNamespace+".yaml"] == "" {
-			return false
+func countDownscaledWorkloads(workloads []shared.Workload) (downscaled, total int) {
+	for _, workload := range workloads {
+		if workload.Phase == shared.PhaseDownscaled {
+			downscaled++
 		}
+		total++
 	}
-
-	return true
-}
-
-func extractSegments(apps shared.Apps) []string {
-	cmData := make([]string, len(apps.State))
-	for i, value := range apps.State {
-		parts := strings.Split(value, ",")
-		cmData[i] = fmt.Sprintf("%s,%s,%s", parts[0], parts[1], parts[2])
-	}
-
-	return cmData
-}
-
-func getNamespaceWithYamlExt(namespace string) string {
-	return namespace + ".yaml"
-}
-
-func createConfigMapKeyForPatching(key string) ([]byte, error) {
-	data := &corev1.ConfigMap{
-		Data: map[string]string{
-			key: "",
-		},
-	}
-
-	patch, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-
-	return patch, nil
+	return downscaled, total
 }
 
-func parseReplicaState(deploymentWithState []string) (int, int, error) {
-	replicaCountSum := 0
-	notEmptyIndex := 0
-	for _, appDeploymentWithState := range deploymentWithState {
-		replicasStr := strings.TrimSpace(strings.Split(appDeploymentWithState, ",")[2])
-		replicasInt, err := strconv.Atoi(replicasStr)
-		if err != nil {
-			slog.Error("conversion error", "err", err)
-			return 0, 0, err
-		}
-		replicaCountSum += replicasInt
-		notEmptyIndex++
-	}
-	return replicaCountSum, notEmptyIndex, nil
+func (t SchedulerTask) Name() string {
+	return fmt.Sprintf("%s %s", strings.Join(t.Namespaces, ","), t.WithCron)
 }
 
 func separatedScheduledNamespaces(rules shared.DownscalerRules) map[string]struct{} {
@@ -100,6 +55,25 @@ func generateScheduledNamespaces(scheduledNamespaces []string) map[string]struct
 	return scheduledNamespacesMap
 }
 
+func parseUpscaleSpread(rule shared.Rule) shared.UpscaleSpread {
+	if rule.UpscaleSpread == "" {
+		return shared.UpscaleSpread{}
+	}
+
+	duration, err := time.ParseDuration(rule.UpscaleSpread)
+	if err != nil {
+		slog.Error("upscale spread parsing error", "spread received", rule.UpscaleSpread, "err", err)
+		return shared.UpscaleSpread{}
+	}
+
+	mode := rule.UpscaleSpreadMode
+	if mode == "" {
+		mode = shared.SpreadModeEven
+	}
+
+	return shared.UpscaleSpread{Duration: duration, Mode: mode}
+}
+
 func shouldConvertTimeFormat(timeFromRules string) bool {
 	return strings.Contains(timeFromRules, "PM")
 }
@@ -231,27 +205,44 @@ func (c *Scheduler) now() time.Time {
 	return time.Now().In(c.Location)
 }
 
-func logWaitRecurrenceDaysWithSleep(now time.Weekday) {
+func sleepOrStop(stopch <-chan struct{}, duration time.Duration) {
+	select {
+	case <-time.After(duration):
+	case <-stopch:
+	}
+}
+
+func logWaitMixedStateWithSleep(stopch <-chan struct{}, namespaces []string) {
+	slog.Warn("task", "namespace(s)", namespaces, "status", "workloads in mixed phases", "action", "waiting", "next retry", "1 minute")
+	sleepOrStop(stopch, time.Minute*1)
+}
+
+func logWaitRecurrenceDaysWithSleep(stopch <-chan struct{}, now time.Weekday) {
 	slog.Info("time", "today is", now.String(), "recurrence days range", "false", "action", "waiting", "next try", "1 minute")
-	time.Sleep(time.Minute * 1)
+	sleepOrStop(stopch, time.Minute*1)
+}
+
+func logWaitOverriddenNamespacesWithSleep(stopch <-chan struct{}, namespaces []string) {
+	slog.Info("task", "namespace(s)", namespaces, "status", "every namespace overridden", "next retry", "1 minute")
+	sleepOrStop(stopch, time.Minute*1)
 }
 
-func logWaitBeforeDownscalingWithSleep(now time.Time, targetTimeToDownscaleFromConfig string, namespaces []string) {
+func logWaitBeforeDownscalingWithSleep(stopch <-chan struct{}, now time.Time, targetTimeToDownscaleFromConfig string, namespaces []string) {
 	var (
 		nowStringFormatted   = fmt.Sprintf("%02d:%02d", now.Hour(), now.Minute())
 		targetTimeToDowscale = strings.Split(targetTimeToDownscaleFromConfig, "-")
 	)
 
 	slog.Info("task", "current time", nowStringFormatted, "provided crontime", targetTimeToDowscale[1], "status", "before downscaling", "next retry", "1 minute", "namespace(s)", namespaces)
-	time.Sleep(time.Minute * 1)
+	sleepOrStop(stopch, time.Minute*1)
 }
 
-func logWaitAfterDownscalingWithSleep(now time.Time, targetTimeToUpscaleFromConfig string, namespaces []string) {
+func logWaitAfterDownscalingWithSleep(stopch <-chan struct{}, now time.Time, targetTimeToUpscaleFromConfig string, namespaces []string) {
 	var (
 		nowStringFormatted  = fmt.Sprintf("%02d:%02d", now.Hour(), now.Minute())
 		targetTimeToUpscale = strings.Split(targetTimeToUpscaleFromConfig, "-")
 	)
 
 	slog.Info("task", "current time", nowStringFormatted, "provided crontime", targetTimeToUpscale[0], "status", "after downscaling", "next retry", "1 minute", "namespace(s)", namespaces)
-	time.Sleep(time.Minute * 1)
+	sleepOrStop(stopch, time.Minute*1)
 }
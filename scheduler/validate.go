@@ -18,7 +18,7 @@ func (v *Scheduler) ignoredNamespacesCleanupValidation(in map[string]struct{}) {
 	v.IgnoredNamespaces = in
 }
 
-func (c *Scheduler) validateSchedulerNamespaces(ctx context.Context, cronTaskNamespaces []string) bool {
+func (c *Scheduler) validateSchedulerNamespaces(ctx context.Context, stopch <-chan struct{}, cronTaskNamespaces []string) bool {
 	k8sNamespaces := c.Kubernetes.GetNamespaces(ctx)
 
 	k8sNamespaceSet := make(map[string]struct{})
@@ -36,7 +36,7 @@ func (c *Scheduler) validateSchedulerNamespaces(ctx context.Context, cronTaskNam
 				"error", ErrNamespaceFromConfigDoNotExists,
 				"next retry", "1 minute",
 			)
-			time.Sleep(time.Minute * 1)
+			sleepOrStop(stopch, time.Minute*1)
 			return false
 		}
 	}
@@ -59,37 +59,3 @@ func validateIfShouldRunDownscalingOrWait(now time.Time, currentReplicasState sh
 func stillSameRecurrenceTime(currentRecurrence, newRecurrence string) bool {
 	return currentRecurrence != "" && strings.EqualFold(currentRecurrence, newRecurrence)
 }
-
-func validateCondition(condition bool, errorsMsg string) string {
-	if !condition {
-		return errorsMsg
-	}
-	return ""
-}
-
-func (c *Scheduler) Validate(downscalerData *shared.DownscalerPolicy) []string {
-	errors := make([]string, 0)
-	timeBlock := downscalerData.Spec.ExecutionOpts.Time
-	expressionBlock := downscalerData.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions
-	rules := downscalerData.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules
-
-	if err := validateCondition(timeBlock.TimeZone != "", ErrTimeZoneNotFound); err != "" {
-		errors = append(errors, err)
-	}
-	if err := validateCondition(timeBlock.Recurrence != "", ErrRecurrenceTimeNotFound); err != "" {
-		errors = append(errors, err)
-	}
-	if err := validateCondition(expressionBlock.Key == "namespace", ErrNotValidExpressionKey); err != "" {
-		errors = append(errors, err)
-	}
-	if err := validateCondition(len(rules) > 0, ErrEmptyRules); err != "" {
-		errors = append(errors, err)
-	}
-	if err := validateCondition(
-		expressionBlock.Operator == "exclude", ErrNotValidExpressionKey,
-	); err != "" {
-		errors = append(errors, err)
-	}
-
-	return errors
-}
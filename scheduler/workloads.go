@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/metrics"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/state"
+	"github.com/adalbertjnr/downscaler/status"
+	v1 "k8s.io/api/apps/v1"
+)
+
+type workloadChanges struct {
+	adopted []v1.Deployment
+	pruned  []shared.Workload
+}
+
+func (c *Scheduler) trackWorkloadChanges(task SchedulerTask, namespaces []string) {
+	if !c.input.RunUpscaling {
+		return
+	}
+
+	for _, namespace := range namespaces {
+		if _, ignored := c.IgnoredNamespaces[namespace]; ignored || namespace == shared.DownscalerNamespace {
+			continue
+		}
+
+		namespaceState, err := c.State.Get(c.ctx, namespace)
+		if errors.Is(err, state.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.Error("workloads", "namespace", namespace, "verb", "read state", "err", err)
+			continue
+		}
+
+		deployments, err := c.Kubernetes.GetDeployments(c.ctx, namespace)
+		if err != nil {
+			continue
+		}
+
+		changes := diffWorkloads(namespaceState.Apps, deployments.Items)
+		if len(changes.adopted) == 0 && len(changes.pruned) == 0 {
+			continue
+		}
+
+		apps := c.applyWorkloadChanges(task, namespace, namespaceState.Apps, changes)
+
+		ctx, cancel := c.persistContext()
+		err = c.writeStateByNamespace(ctx, map[string]shared.Apps{namespace: apps}, map[string]*state.NamespaceState{namespace: namespaceState})
+		cancel()
+		if err != nil {
+			slog.Error("workloads", "namespace", namespace, "verb", "write state", "err", err)
+			c.reportNamespaceError(task, []string{namespace}, err)
+			continue
+		}
+		metrics.NamespaceState(namespace, apps)
+	}
+}
+
+func (c *Scheduler) applyWorkloadChanges(task SchedulerTask, namespace string, apps shared.Apps, changes workloadChanges) shared.Apps {
+	pruned := make(map[string]struct{}, len(changes.pruned))
+	for _, workload := range changes.pruned {
+		pruned[workload.Name+"/"+workload.UID] = struct{}{}
+		slog.Info("workloads", "name", workload.Name, "namespace", namespace, "uid", workload.UID, "status", "deleted while downscaled", "action", "pruned from the state")
+		c.Status.WorkloadChanged(namespace, workload.Name, status.WorkloadPruned, workload.OriginalReplicas)
+	}
+
+	updated := apps
+	updated.State = make([]shared.Workload, 0, len(apps.State)+len(changes.adopted))
+	for _, workload := range apps.State {
+		if _, found := pruned[workload.Name+"/"+workload.UID]; !found {
+			updated.State = append(updated.State, workload)
+		}
+	}
+
+	patch, err := kas.GenerateScalePatch(0)
+	if err != nil {
+		slog.Error("workloads", "namespace", namespace, "verb", "generate patch", "err", err)
+		return updated
+	}
+
+	for _, deployment := range changes.adopted {
+		replicas := *deployment.Spec.Replicas
+		slog.Info("workloads", "name", deployment.Name, "namespace", namespace, "replicas", replicas, "status", "created while downscaled", "action", "downscaling")
+		workload := shared.Workload{
+			Kind:             shared.DeploymentKind,
+			Name:             deployment.Name,
+			UID:              string(deployment.UID),
+			OriginalReplicas: replicas,
+			ScaledAt:         time.Now().UTC(),
+			ScaledBy:         task.Name(),
+			TemplateHash:     kas.TemplateHash(&deployment),
+			Phase:            shared.PhaseDownscaled,
+		}
+		if err := c.Kubernetes.ScaleDeployments(c.ctx, namespace, &deployment, patch, 0, task.Name()); err != nil {
+			slog.Warn("workloads", "name", deployment.Name, "namespace", namespace, "status", "failed", "next retry", "next cycle")
+			workload.Phase = shared.PhaseUpscaled
+			workload.LastError = err.Error()
+		}
+
+		updated.State = append(updated.State, workload)
+		c.Status.WorkloadChanged(namespace, deployment.Name, status.WorkloadAdopted, replicas)
+	}
+
+	if len(updated.State) > 0 {
+		updated.Status = shared.NotEmptyNamespace
+	}
+	return updated
+}
+
+func diffWorkloads(apps shared.Apps, deployments []v1.Deployment) workloadChanges {
+	live := make(map[string]*v1.Deployment, len(deployments))
+	for i := range deployments {
+		live[deployments[i].Name] = &deployments[i]
+	}
+
+	recorded := make(map[string]shared.Workload, len(apps.State))
+	changes := workloadChanges{}
+	for _, workload := range apps.State {
+		recorded[workload.Name] = workload
+		if deployment, found := live[workload.Name]; !found || !workload.Matches(deployment.Name, string(deployment.UID)) {
+			changes.pruned = append(changes.pruned, workload)
+		}
+	}
+
+	for _, deployment := range deployments {
+		if workload, found := recorded[deployment.Name]; found && workload.Matches(deployment.Name, string(deployment.UID)) {
+			continue
+		}
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
+			continue
+		}
+		changes.adopted = append(changes.adopted, deployment)
+	}
+	return changes
+}
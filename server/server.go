@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const shutdownTimeout = time.Second * 5
+
+type Server struct {
+	address      string
+	mux          *http.ServeMux
+	certificates *certificateReloader
+}
+
+func New(address string) *Server {
+	return &Server{
+		address: address,
+		mux:     http.NewServeMux(),
+	}
+}
+
+func (s *Server) AddHandler(pattern string, handler http.Handler) *Server {
+	s.mux.Handle(pattern, handler)
+	return s
+}
+
+func (s *Server) AddTLS(certDir string) *Server {
+	s.certificates = &certificateReloader{
+		certFile: filepath.Join(certDir, "tls.crt"),
+		keyFile:  filepath.Join(certDir, "tls.key"),
+	}
+	return s
+}
+
+func (s *Server) Run(ctx context.Context) {
+	srv := &http.Server{
+		Addr:              s.address,
+		Handler:           s.mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	listen := srv.ListenAndServe
+	if s.certificates != nil {
+		if _, err := s.certificates.GetCertificate(nil); err != nil {
+			slog.Error("http server", "address", s.address, "cert", s.certificates.certFile, "err", err)
+			return
+		}
+		srv.TLSConfig = &tls.Config{GetCertificate: s.certificates.GetCertificate, MinVersion: tls.VersionTLS12}
+		listen = func() error { return srv.ListenAndServeTLS("", "") }
+	}
+
+	slog.Info("http server", "address", s.address, "tls", s.certificates != nil, "status", "listening")
+	if err := listen(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("http server", "address", s.address, "err", err)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		if r.certificate != nil {
+			return r.certificate, nil
+		}
+		return nil, err
+	}
+	if r.certificate != nil && info.ModTime().Equal(r.modTime) {
+		return r.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.certificate != nil {
+			slog.Error("http server", "cert", r.certFile, "verb", "reload certificate", "err", err)
+			return r.certificate, nil
+		}
+		return nil, err
+	}
+
+	if r.certificate != nil {
+		slog.Info("http server", "cert", r.certFile, "status", "certificate reloaded")
+	}
+	r.certificate = &certificate
+	r.modTime = info.ModTime()
+	return r.certificate, nil
+}
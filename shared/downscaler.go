@@ -1,9 +1,13 @@
 package shared
 
 const (
-	Version  = "v1"
-	Resource = "downscalers"
-	Group    = "scheduler.go"
+	Version            = "v1"
+	Resource           = "downscalers"
+	NamespacedResource = "namespacedownscalers"
+	Group              = "scheduler.go"
+
+	DownscalerKind          = "Downscaler"
+	NamespaceDownscalerKind = "NamespaceDownscaler"
 
 	EmptyNamespace    = "empty"
 	NotEmptyNamespace = "not_empty"
@@ -13,6 +17,19 @@ const (
 
 	DefaultGroup     = "default"
 	UnspecifiedGroup = "unspecified"
+
+	StateBackendConfigMap   = "configmap"
+	StateBackendAnnotations = "annotations"
+	StateBackendStatus      = "status"
+	StateBackendMemory      = "memory"
+
+	OriginalReplicasAnnotation = "downscaler/original-replicas"
+	DownscaledAtAnnotation     = "downscaler/downscaled-at"
+	TemplateHashAnnotation     = "downscaler/template-hash"
+	LastErrorAnnotation        = "downscaler/last-error"
+
+	UserAgent    = "downscaler"
+	CtlUserAgent = "downscalerctl"
 )
 
 type TaskControl int
@@ -29,15 +46,12 @@ const (
 	AppStartupWithNoDataWrite
 
 	UpscalingDeactivated
-)
 
-type Apps struct {
-	Status string   `yaml:"status"`
-	Group  string   `yaml:"group"`
-	State  []string `yaml:"state"`
-}
+	DeploymentsWithMixedState
+)
 
 type Metadata struct {
+	Resource  string
 	Name      string
 	Namespace string
 }
@@ -45,7 +59,11 @@ type Metadata struct {
 type DownscalerPolicy struct {
 	Kind     string `yaml:"kind"`
 	Metadata struct {
-		Name string `yaml:"name"`
+		Name              string `yaml:"name"`
+		Namespace         string `yaml:"namespace"`
+		Generation        int64  `yaml:"generation"`
+		UID               string `yaml:"uid"`
+		CreationTimestamp string `yaml:"creationTimestamp"`
 	}
 	Spec struct {
 		ExecutionOpts struct {
@@ -62,10 +80,8 @@ type DownscalerPolicy struct {
 					} `yaml:"downscalerSelectorTerms"`
 					WithNamespaceOpts struct {
 						DownscaleNamespacesWithTimeRules struct {
-							Rules []struct {
-								Namespaces []string `yaml:"namespaces"`
-								WithCron   string   `yaml:"withCron"`
-							} `yaml:"rules"`
+							OverlapResolution string `yaml:"overlapResolution"`
+							Rules             []Rule `yaml:"rules"`
 						} `yaml:"downscaleNamespacesWithTimeRules"`
 					} `yaml:"withNamespaceOpts"`
 				} `yaml:"downscaler"`
@@ -74,6 +90,20 @@ type DownscalerPolicy struct {
 	} `yaml:"spec"`
 }
 
+func (p *DownscalerPolicy) Key() string {
+	if p.Kind == NamespaceDownscalerKind {
+		return PolicyKey(p.Metadata.Namespace, p.Metadata.Name)
+	}
+	return p.Metadata.Name
+}
+
+func PolicyKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
 func (n NotUsableNamespacesDuringScheduling) Validate(namespaces []string) bool {
 	for _, namespace := range namespaces {
 		if namespace == Unspecified {
@@ -36,11 +36,16 @@ func (v *DownscalerExpression) ShowIgnoredNamespaces(ignoredNamespaces []string)
 	return nil
 }
 
+type Rule struct {
+	Namespaces        []string `yaml:"namespaces"`
+	WithCron          string   `yaml:"withCron"`
+	UpscaleSpread     string   `yaml:"upscaleSpread"`
+	UpscaleSpreadMode string   `yaml:"upscaleSpreadMode"`
+}
+
 type DownscalerRules struct {
-	Rules []struct {
-		Namespaces []string `yaml:"namespaces"`
-		WithCron   string   `yaml:"withCron"`
-	} `yaml:"rules"`
+	OverlapResolution string `yaml:"overlapResolution"`
+	Rules             []Rule `yaml:"rules"`
 }
 
 func (v *DownscalerRules) Available() bool {
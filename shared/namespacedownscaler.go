@@ -0,0 +1,41 @@
+package shared
+
+type NamespaceDownscalerPolicy struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name              string `yaml:"name"`
+		Namespace         string `yaml:"namespace"`
+		Generation        int64  `yaml:"generation"`
+		UID               string `yaml:"uid"`
+		CreationTimestamp string `yaml:"creationTimestamp"`
+	}
+	Spec struct {
+		TimeZone          string `yaml:"timeZone"`
+		Recurrence        string `yaml:"recurrence"`
+		WithCron          string `yaml:"withCron"`
+		UpscaleSpread     string `yaml:"upscaleSpread"`
+		UpscaleSpreadMode string `yaml:"upscaleSpreadMode"`
+	} `yaml:"spec"`
+}
+
+func (p *NamespaceDownscalerPolicy) DownscalerPolicy() *DownscalerPolicy {
+	policy := &DownscalerPolicy{Kind: NamespaceDownscalerKind}
+	policy.Metadata.Name = p.Metadata.Name
+	policy.Metadata.Namespace = p.Metadata.Namespace
+	policy.Metadata.Generation = p.Metadata.Generation
+	policy.Metadata.UID = p.Metadata.UID
+	policy.Metadata.CreationTimestamp = p.Metadata.CreationTimestamp
+
+	schedule := &policy.Spec.ExecutionOpts.Time
+	schedule.TimeZone = p.Spec.TimeZone
+	schedule.Recurrence = p.Spec.Recurrence
+	schedule.Downscaler.DownscalerSelectorTerms.MatchExpressions.Key = "namespace"
+	schedule.Downscaler.DownscalerSelectorTerms.MatchExpressions.Operator = "exclude"
+	schedule.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = []Rule{{
+		Namespaces:        []string{p.Metadata.Namespace},
+		WithCron:          p.Spec.WithCron,
+		UpscaleSpread:     p.Spec.UpscaleSpread,
+		UpscaleSpreadMode: p.Spec.UpscaleSpreadMode,
+	}}
+	return policy
+}
@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	OverrideAnnotation      = "downscaler/override"
+	OverrideUntilAnnotation = "downscaler/override-until"
+
+	OverrideWake  = "wake"
+	OverrideSleep = "sleep"
+)
+
+type Override struct {
+	Mode  string
+	Until time.Time
+}
+
+func (o Override) Active(now time.Time) bool {
+	return now.Before(o.Until)
+}
+
+func (o Override) String() string {
+	return fmt.Sprintf("%s override until %s", o.Mode, o.Until.UTC().Format(time.RFC3339))
+}
+
+func ParseOverride(annotations map[string]string) (Override, bool) {
+	mode, found := annotations[OverrideAnnotation]
+	if !found || (mode != OverrideWake && mode != OverrideSleep) {
+		return Override{}, false
+	}
+
+	until, err := time.Parse(time.RFC3339, annotations[OverrideUntilAnnotation])
+	if err != nil {
+		return Override{}, false
+	}
+
+	return Override{Mode: mode, Until: until}, true
+}
@@ -1,5 +1,11 @@
 package shared
 
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
 const (
 	ExpectedTimeParts   = 2
 	TimeFormat          = "15:04"
@@ -7,4 +13,46 @@ const (
 	TimeFormat12Down    = "03:04PM"
 	Default24TimeFormat = "default24format"
 	Default12TimeFormat = "default12format"
+
+	SpreadModeEven   = "even"
+	SpreadModeJitter = "jitter"
+
+	OverlapReject          = "reject"
+	OverlapFirstRule       = "firstRule"
+	OverlapMostRestrictive = "mostRestrictive"
+	OverlapLongestUptime   = "longestUptime"
+
+	ScaledByDownscalerDeleted = "downscaler deleted"
+
+	StateFlushTimeout = 10 * time.Second
 )
+
+type UpscaleSpread struct {
+	Duration time.Duration
+	Mode     string
+}
+
+func (s UpscaleSpread) Enabled() bool {
+	return s.Duration > 0
+}
+
+func (s UpscaleSpread) Offsets(workloads int) []time.Duration {
+	offsets := make([]time.Duration, workloads)
+	if !s.Enabled() || workloads == 0 {
+		return offsets
+	}
+
+	if s.Mode == SpreadModeJitter {
+		for i := range offsets {
+			offsets[i] = time.Duration(rand.Int63n(int64(s.Duration)))
+		}
+		sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+		return offsets
+	}
+
+	step := s.Duration / time.Duration(workloads)
+	for i := range offsets {
+		offsets[i] = step * time.Duration(i)
+	}
+	return offsets
+}
@@ -0,0 +1,47 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpscaleSpreadOffsets(t *testing.T) {
+	tests := []struct {
+		name      string
+		spread    UpscaleSpread
+		workloads int
+		expected  []time.Duration
+	}{
+		{"Without spread every workload starts at once", UpscaleSpread{}, 3, []time.Duration{0, 0, 0}},
+		{"With even spread the workloads are evenly distributed", UpscaleSpread{Duration: time.Minute * 30, Mode: SpreadModeEven}, 3, []time.Duration{0, time.Minute * 10, time.Minute * 20}},
+		{"With even spread and no workloads", UpscaleSpread{Duration: time.Minute * 30, Mode: SpreadModeEven}, 0, []time.Duration{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offsets := tt.spread.Offsets(tt.workloads)
+			if len(offsets) != len(tt.expected) {
+				t.Fatalf("Offsets(%d) returned %d offsets; expected %d", tt.workloads, len(offsets), len(tt.expected))
+			}
+			for i := range offsets {
+				if offsets[i] != tt.expected[i] {
+					t.Errorf("Offsets(%d)[%d] = %v; expected %v", tt.workloads, i, offsets[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUpscaleSpreadJitterOffsets(t *testing.T) {
+	spread := UpscaleSpread{Duration: time.Minute * 10, Mode: SpreadModeJitter}
+
+	offsets := spread.Offsets(50)
+	for i, offset := range offsets {
+		if offset < 0 || offset >= spread.Duration {
+			t.Errorf("Offsets(50)[%d] = %v; expected within [0, %v)", i, offset, spread.Duration)
+		}
+		if i > 0 && offset < offsets[i-1] {
+			t.Errorf("Offsets(50)[%d] = %v is before the previous offset %v", i, offset, offsets[i-1])
+		}
+	}
+}
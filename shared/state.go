@@ -0,0 +1,162 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LegacyStateSchemaVersion = 1
+	StateSchemaVersion       = 2
+
+	DeploymentKind = "Deployment"
+)
+
+type Phase string
+
+const (
+	PhaseDownscaled Phase = "Downscaled"
+	PhaseUpscaled   Phase = "Upscaled"
+)
+
+type Apps struct {
+	SchemaVersion int        `yaml:"schemaVersion" json:"schemaVersion"`
+	Status        string     `yaml:"status" json:"status"`
+	Group         string     `yaml:"group" json:"group"`
+	State         []Workload `yaml:"state" json:"state"`
+	Unparsed      []string   `yaml:"unparsed,omitempty" json:"unparsed,omitempty"`
+}
+
+type Workload struct {
+	Kind             string    `yaml:"kind" json:"kind"`
+	Name             string    `yaml:"name" json:"name"`
+	UID              string    `yaml:"uid,omitempty" json:"uid,omitempty"`
+	OriginalReplicas int32     `yaml:"originalReplicas" json:"originalReplicas"`
+	ScaledAt         time.Time `yaml:"scaledAt" json:"scaledAt"`
+	ScaledBy         string    `yaml:"scaledBy,omitempty" json:"scaledBy,omitempty"`
+	TemplateHash     string    `yaml:"templateHash,omitempty" json:"templateHash,omitempty"`
+	Phase            Phase     `yaml:"phase" json:"phase"`
+	LastError        string    `yaml:"lastError,omitempty" json:"lastError,omitempty"`
+}
+
+type legacyApps struct {
+	Status string   `yaml:"status" json:"status"`
+	Group  string   `yaml:"group" json:"group"`
+	State  []string `yaml:"state" json:"state"`
+}
+
+type appsSchema Apps
+
+func WorkloadKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (w Workload) Matches(name, uid string) bool {
+	return w.Name == name && (w.UID == "" || uid == "" || w.UID == uid)
+}
+
+// a failed scaling keeps the previous phase, so the phase it was heading to is the other one
+func (w Workload) Failed() bool {
+	return w.LastError != ""
+}
+
+func (w Workload) TargetPhase() Phase {
+	if w.Phase == PhaseDownscaled {
+		return PhaseUpscaled
+	}
+	return PhaseDownscaled
+}
+
+func (w Workload) TargetReplicas() int32 {
+	if w.TargetPhase() == PhaseDownscaled {
+		return 0
+	}
+	return w.OriginalReplicas
+}
+
+func (a Apps) NeedsMigration() bool {
+	return a.SchemaVersion < StateSchemaVersion
+}
+
+func (a *Apps) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var typed appsSchema
+	if err := unmarshal(&typed); err == nil {
+		*a = Apps(typed)
+		return nil
+	}
+
+	var legacy legacyApps
+	if err := unmarshal(&legacy); err != nil {
+		return err
+	}
+	*a = migrateLegacyApps(legacy)
+	return nil
+}
+
+func (a *Apps) UnmarshalJSON(data []byte) error {
+	var typed appsSchema
+	if err := json.Unmarshal(data, &typed); err == nil {
+		*a = Apps(typed)
+		return nil
+	}
+
+	var legacy legacyApps
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	*a = migrateLegacyApps(legacy)
+	return nil
+}
+
+func migrateLegacyApps(legacy legacyApps) Apps {
+	apps := Apps{
+		SchemaVersion: LegacyStateSchemaVersion,
+		Status:        legacy.Status,
+		Group:         legacy.Group,
+		State:         make([]Workload, 0, len(legacy.State)),
+	}
+
+	for _, entry := range legacy.State {
+		workload, err := ParseLegacyWorkload(entry)
+		if err != nil {
+			slog.Error("state migration", "entry", entry, "status", "kept unparsed", "err", err)
+			apps.Unparsed = append(apps.Unparsed, entry)
+			continue
+		}
+		apps.State = append(apps.State, workload)
+	}
+	return apps
+}
+
+func ParseLegacyWorkload(entry string) (Workload, error) {
+	parts := strings.Split(entry, ",")
+	if len(parts) != 3 {
+		return Workload{}, fmt.Errorf("malformed state entry %q", entry)
+	}
+
+	replicas, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Workload{}, fmt.Errorf("malformed replicas in state entry %q: %v", entry, err)
+	}
+
+	flag, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return Workload{}, fmt.Errorf("malformed state in state entry %q: %v", entry, err)
+	}
+
+	phase := PhaseUpscaled
+	if TaskControl(flag) == DeploymentsWithDownscaledState {
+		phase = PhaseDownscaled
+	}
+
+	return Workload{
+		Kind:             DeploymentKind,
+		Name:             strings.TrimSpace(parts[0]),
+		OriginalReplicas: int32(replicas),
+		Phase:            phase,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestAppsMigratesLegacyState(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmarshal func(data []byte, apps *Apps) error
+		data      string
+	}{
+		{"From yaml", func(data []byte, apps *Apps) error { return yaml.Unmarshal(data, apps) }, "status: not_empty\ngroup: default\nstate:\n- api,3,3\n- broken\n- worker,1,4\n"},
+		{"From json", func(data []byte, apps *Apps) error { return json.Unmarshal(data, apps) }, `{"status":"not_empty","group":"default","state":["api,3,3","broken","worker,1,4"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apps Apps
+			if err := tt.unmarshal([]byte(tt.data), &apps); err != nil {
+				t.Fatalf("unmarshal error = %v", err)
+			}
+
+			if !apps.NeedsMigration() {
+				t.Errorf("NeedsMigration() = false; expected true for schema version %d", apps.SchemaVersion)
+			}
+			if len(apps.State) != 2 {
+				t.Fatalf("migrated %d workloads; expected 2", len(apps.State))
+			}
+			if len(apps.Unparsed) != 1 || apps.Unparsed[0] != "broken" {
+				t.Errorf("Unparsed = %v; expected the malformed entry to be kept", apps.Unparsed)
+			}
+
+			expected := []Workload{
+				{Kind: DeploymentKind, Name: "api", OriginalReplicas: 3, Phase: PhaseDownscaled},
+				{Kind: DeploymentKind, Name: "worker", OriginalReplicas: 1, Phase: PhaseUpscaled},
+			}
+			for i := range expected {
+				if apps.State[i] != expected[i] {
+					t.Errorf("State[%d] = %+v; expected %+v", i, apps.State[i], expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAppsTypedStateRoundTrip(t *testing.T) {
+	apps := Apps{
+		SchemaVersion: StateSchemaVersion,
+		Status:        NotEmptyNamespace,
+		Group:         DefaultGroup,
+		State:         []Workload{{Kind: DeploymentKind, Name: "api", UID: "1234", OriginalReplicas: 3, ScaledBy: "nginx-1 01:30-14:50", Phase: PhaseDownscaled}},
+	}
+
+	data, err := yaml.Marshal(apps)
+	if err != nil {
+		t.Fatalf("yaml.Marshal error = %v", err)
+	}
+
+	var decoded Apps
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("yaml.Unmarshal error = %v", err)
+	}
+
+	if decoded.NeedsMigration() {
+		t.Errorf("NeedsMigration() = true; expected false for schema version %d", decoded.SchemaVersion)
+	}
+	if len(decoded.State) != 1 || decoded.State[0].UID != "1234" || decoded.State[0].ScaledBy != "nginx-1 01:30-14:50" {
+		t.Errorf("State = %+v; expected the written workload", decoded.State)
+	}
+}
+
+func TestWorkloadRetryTarget(t *testing.T) {
+	tests := []struct {
+		name             string
+		workload         Workload
+		expectedPhase    Phase
+		expectedReplicas int32
+	}{
+		{"Failed downscaling", Workload{Name: "api", OriginalReplicas: 3, Phase: PhaseUpscaled, LastError: "timeout"}, PhaseDownscaled, 0},
+		{"Failed upscaling", Workload{Name: "api", OriginalReplicas: 3, Phase: PhaseDownscaled, LastError: "timeout"}, PhaseUpscaled, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.workload.Failed() {
+				t.Errorf("Failed() = false; expected true with LastError %q", tt.workload.LastError)
+			}
+			if phase := tt.workload.TargetPhase(); phase != tt.expectedPhase {
+				t.Errorf("TargetPhase() = %s; expected %s", phase, tt.expectedPhase)
+			}
+			if replicas := tt.workload.TargetReplicas(); replicas != tt.expectedReplicas {
+				t.Errorf("TargetReplicas() = %d; expected %d", replicas, tt.expectedReplicas)
+			}
+		})
+	}
+}
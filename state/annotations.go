@@ -0,0 +1,207 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+type DeploymentLister interface {
+	Synced() bool
+	Deployments(namespace string) (*v1.DeploymentList, error)
+}
+
+type AnnotationStore struct {
+	client kubernetes.Interface
+	lister DeploymentLister
+}
+
+func NewAnnotationStore(client kubernetes.Interface) *AnnotationStore {
+	return &AnnotationStore{client: client}
+}
+
+func (s *AnnotationStore) AddLister(lister DeploymentLister) *AnnotationStore {
+	s.lister = lister
+	return s
+}
+
+// reads are served from the lister once it is synced, GetWorkload still reads the api
+// because the resource version it returns is the precondition of the annotation patch
+func (s *AnnotationStore) deployments(ctx context.Context, namespace string) (*v1.DeploymentList, error) {
+	if s.lister != nil && s.lister.Synced() {
+		deployments, err := s.lister.Deployments(namespace)
+		if err != nil {
+			slog.Error("deployments", "verb", "list", "namespace", namespace, "source", "cache", "err", err)
+			return nil, err
+		}
+		return deployments, nil
+	}
+
+	deployments, err := s.client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Error("deployments", "verb", "list", "namespace", namespace, "err", err)
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (s *AnnotationStore) Get(ctx context.Context, namespace string) (*NamespaceState, error) {
+	deployments, err := s.deployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	states := groupAnnotatedByNamespace(deployments.Items)
+	if state, found := states[namespace]; found {
+		return state, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *AnnotationStore) Put(ctx context.Context, state *NamespaceState) error {
+	for _, workload := range state.Apps.State {
+		if err := s.PutWorkload(ctx, state.Namespace, workload, ""); err != nil {
+			if apierrors.IsNotFound(err) || errors.Is(err, ErrNotFound) {
+				slog.Warn("deployments", "name", workload.Name, "namespace", state.Namespace, "uid", workload.UID, "verb", "annotate", "status", "skipped", "reason", "not found")
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AnnotationStore) List(ctx context.Context) ([]*NamespaceState, error) {
+	deployments, err := s.deployments(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*NamespaceState, 0)
+	for _, state := range groupAnnotatedByNamespace(deployments.Items) {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *AnnotationStore) GetWorkload(ctx context.Context, namespace, name string) (*shared.Workload, string, error) {
+	deployment, err := s.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	workload, found := workloadFromAnnotations(deployment)
+	if !found {
+		return nil, "", ErrNotFound
+	}
+	return &workload, deployment.ResourceVersion, nil
+}
+
+func (s *AnnotationStore) PutWorkload(ctx context.Context, namespace string, workload shared.Workload, resourceVersion string) error {
+	annotations := map[string]interface{}{
+		shared.OriginalReplicasAnnotation: nil,
+		shared.DownscaledAtAnnotation:     nil,
+		shared.TemplateHashAnnotation:     nil,
+		shared.LastErrorAnnotation:        nil,
+	}
+	if workload.Failed() {
+		annotations[shared.OriginalReplicasAnnotation] = strconv.Itoa(int(workload.OriginalReplicas))
+		annotations[shared.LastErrorAnnotation] = workload.LastError
+	}
+	if workload.Phase == shared.PhaseDownscaled {
+		scaledAt := workload.ScaledAt
+		if scaledAt.IsZero() {
+			scaledAt = time.Now()
+		}
+		annotations[shared.OriginalReplicasAnnotation] = strconv.Itoa(int(workload.OriginalReplicas))
+		annotations[shared.DownscaledAtAnnotation] = scaledAt.UTC().Format(time.RFC3339)
+		if workload.TemplateHash != "" {
+			annotations[shared.TemplateHashAnnotation] = workload.TemplateHash
+		}
+	}
+
+	metadata := map[string]interface{}{"annotations": annotations}
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+	if workload.UID != "" {
+		metadata["uid"] = workload.UID
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.AppsV1().Deployments(namespace).Patch(ctx, workload.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsConflict(err) {
+		return ErrConflict
+	}
+	if apierrors.IsInvalid(err) && workload.UID != "" {
+		return fmt.Errorf("%w: deployment %s/%s was recreated", ErrNotFound, namespace, workload.Name)
+	}
+	return err
+}
+
+func workloadFromAnnotations(deployment *v1.Deployment) (shared.Workload, bool) {
+	value, found := deployment.Annotations[shared.OriginalReplicasAnnotation]
+	if !found {
+		return shared.Workload{}, false
+	}
+
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("annotation conversion error", "name", deployment.Name, "namespace", deployment.Namespace, "annotation", shared.OriginalReplicasAnnotation, "value", value, "err", err)
+		return shared.Workload{}, false
+	}
+
+	phase := shared.PhaseDownscaled
+	downscaledAt, downscaled := deployment.Annotations[shared.DownscaledAtAnnotation]
+	lastError, failed := deployment.Annotations[shared.LastErrorAnnotation]
+	if failed && !downscaled {
+		phase = shared.PhaseUpscaled
+	}
+	scaledAt, _ := time.Parse(time.RFC3339, downscaledAt)
+
+	return shared.Workload{
+		Kind:             shared.DeploymentKind,
+		Name:             deployment.Name,
+		UID:              string(deployment.UID),
+		OriginalReplicas: int32(replicas),
+		ScaledAt:         scaledAt,
+		TemplateHash:     deployment.Annotations[shared.TemplateHashAnnotation],
+		Phase:            phase,
+		LastError:        lastError,
+	}, true
+}
+
+func groupAnnotatedByNamespace(deployments []v1.Deployment) map[string]*NamespaceState {
+	states := make(map[string]*NamespaceState)
+	for i := range deployments {
+		workload, found := workloadFromAnnotations(&deployments[i])
+		if !found {
+			continue
+		}
+
+		namespace := deployments[i].Namespace
+		if _, exists := states[namespace]; !exists {
+			states[namespace] = emptyNamespaceState(namespace)
+		}
+		states[namespace].Apps.State = append(states[namespace].Apps.State, workload)
+	}
+	return states
+}
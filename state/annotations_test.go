@@ -0,0 +1,52 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type staticDeploymentLister struct {
+	deployments []v1.Deployment
+}
+
+func (l staticDeploymentLister) Synced() bool { return true }
+func (l staticDeploymentLister) Deployments(string) (*v1.DeploymentList, error) {
+	return &v1.DeploymentList{Items: l.deployments}, nil
+}
+
+func TestAnnotationStoreReadsFromLister(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		cached = v1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "nginx-1",
+			Annotations: map[string]string{shared.OriginalReplicasAnnotation: "3", shared.DownscaledAtAnnotation: "2024-06-03T20:00:00Z"},
+		}}
+		client = fake.NewSimpleClientset()
+		store  = NewAnnotationStore(client).AddLister(staticDeploymentLister{deployments: []v1.Deployment{cached}})
+	)
+
+	read, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if len(read.Apps.State) != 1 || read.Apps.State[0].Name != "api" || read.Apps.State[0].Phase != shared.PhaseDownscaled {
+		t.Errorf("Get(nginx-1) state = %v; expected api downscaled", read.Apps.State)
+	}
+
+	states, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(states) != 1 {
+		t.Errorf("len(List()) = %d; expected 1", len(states))
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Get and List made %d api calls; expected the reads to be served from the lister", len(client.Actions()))
+	}
+}
@@ -0,0 +1,224 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const configMapKeySuffix = ".yaml"
+
+type ConfigMapLister interface {
+	Synced() bool
+	CachesConfigMaps(namespace string) bool
+	ConfigMap(name, namespace string) (*corev1.ConfigMap, error)
+}
+
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	lister    ConfigMapLister
+	name      string
+	namespace string
+
+	writtenMu sync.Mutex
+	written   map[string]string
+}
+
+func NewConfigMapStore(client kubernetes.Interface, name, namespace string) *ConfigMapStore {
+	return &ConfigMapStore{
+		client:    client,
+		name:      name,
+		namespace: namespace,
+		written:   make(map[string]string),
+	}
+}
+
+func (s *ConfigMapStore) AddLister(lister ConfigMapLister) *ConfigMapStore {
+	s.lister = lister
+	return s
+}
+
+// reads are served from the lister when it watches the namespace, writes always go
+// through the api. Until the lister has caught up with our own writes the reads go to the
+// api too, otherwise Put would reject the version read from it with ErrConflict
+func (s *ConfigMapStore) configMap(ctx context.Context) (*corev1.ConfigMap, error) {
+	if s.lister != nil && s.lister.Synced() && s.lister.CachesConfigMaps(s.namespace) {
+		cm, err := s.lister.ConfigMap(s.name, s.namespace)
+		if err != nil {
+			slog.Error("configmap", "name", s.name, "namespace", s.namespace, "verb", "get", "source", "cache", "err", err)
+			return nil, err
+		}
+		if !s.listerLags(cm) {
+			if cm == nil {
+				return nil, ErrNotFound
+			}
+			return cm, nil
+		}
+	}
+
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		slog.Error("configmap", "name", s.name, "namespace", s.namespace, "verb", "get", "err", err)
+		return nil, err
+	}
+	return cm, nil
+}
+
+func (s *ConfigMapStore) listerLags(cm *corev1.ConfigMap) bool {
+	s.writtenMu.Lock()
+	defer s.writtenMu.Unlock()
+
+	lags := false
+	for key, version := range s.written {
+		var cached string
+		if cm != nil {
+			cached = cm.Data[key]
+		}
+		if stored, err := parseNamespaceState(strings.TrimSuffix(key, configMapKeySuffix), cached); err == nil && stored.ResourceVersion == version {
+			delete(s.written, key)
+			continue
+		}
+		lags = true
+	}
+	return lags
+}
+
+func (s *ConfigMapStore) wrote(key string, state *NamespaceState) {
+	s.writtenMu.Lock()
+	defer s.writtenMu.Unlock()
+
+	state.ResourceVersion = contentVersion(state.Apps)
+	s.written[key] = state.ResourceVersion
+}
+
+func (s *ConfigMapStore) Get(ctx context.Context, namespace string) (*NamespaceState, error) {
+	cm, err := s.configMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	value := cm.Data[namespace+configMapKeySuffix]
+	if value == "" {
+		return nil, ErrNotFound
+	}
+
+	return parseNamespaceState(namespace, value)
+}
+
+func (s *ConfigMapStore) Put(ctx context.Context, state *NamespaceState) error {
+	data, err := yaml.Marshal(state.Apps)
+	if err != nil {
+		return err
+	}
+	key := state.Namespace + configMapKeySuffix
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return s.create(ctx, state, key, string(data))
+		}
+		if err != nil {
+			return err
+		}
+
+		if state.ResourceVersion != "" {
+			stored, err := parseNamespaceState(state.Namespace, cm.Data[key])
+			if err != nil || stored.ResourceVersion != state.ResourceVersion {
+				return ErrConflict
+			}
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = string(data)
+
+		if _, err := s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			if !apierrors.IsConflict(err) {
+				slog.Error("configmap", "name", s.name, "namespace", s.namespace, "verb", "update", "err", err)
+			}
+			return err
+		}
+
+		s.wrote(key, state)
+		return nil
+	})
+}
+
+func (s *ConfigMapStore) create(ctx context.Context, state *NamespaceState, key, value string) error {
+	if state.ResourceVersion != "" {
+		return ErrConflict
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.name,
+			Namespace: s.namespace,
+		},
+		Data: map[string]string{key: value},
+	}
+	if _, err := s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		slog.Error("configmap", "name", s.name, "namespace", s.namespace, "verb", "create", "err", err)
+		return err
+	}
+
+	s.wrote(key, state)
+	return nil
+}
+
+func (s *ConfigMapStore) List(ctx context.Context) ([]*NamespaceState, error) {
+	cm, err := s.configMap(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*NamespaceState, 0, len(cm.Data))
+	for key, value := range cm.Data {
+		if !strings.HasSuffix(key, configMapKeySuffix) || value == "" {
+			continue
+		}
+		state, err := parseNamespaceState(strings.TrimSuffix(key, configMapKeySuffix), value)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *ConfigMapStore) GetWorkload(ctx context.Context, namespace, name string) (*shared.Workload, string, error) {
+	return getWorkloadFromNamespace(ctx, s, namespace, name)
+}
+
+func (s *ConfigMapStore) PutWorkload(ctx context.Context, namespace string, workload shared.Workload, resourceVersion string) error {
+	return putWorkloadInNamespace(ctx, s, namespace, workload, resourceVersion)
+}
+
+func parseNamespaceState(namespace, value string) (*NamespaceState, error) {
+	var apps shared.Apps
+	if err := yaml.Unmarshal([]byte(value), &apps); err != nil {
+		return nil, err
+	}
+
+	return &NamespaceState{
+		Namespace:       namespace,
+		Apps:            apps,
+		ResourceVersion: contentVersion(apps),
+	}, nil
+}
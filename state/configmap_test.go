@@ -0,0 +1,155 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapStoreRoundTrip(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewConfigMapStore(fake.NewSimpleClientset(), "downscaler-cm", "downscaler")
+	)
+
+	written := &NamespaceState{
+		Namespace: "nginx-1",
+		Apps: shared.Apps{
+			Status: shared.NotEmptyNamespace,
+			Group:  shared.DefaultGroup,
+			State:  []shared.Workload{{Kind: shared.DeploymentKind, Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}},
+		},
+	}
+	if err := store.Put(ctx, written); err != nil {
+		t.Fatalf("Put(nginx-1) error = %v", err)
+	}
+
+	read, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if len(read.Apps.State) != 1 || read.Apps.State[0].Name != "api" || read.Apps.State[0].OriginalReplicas != 3 {
+		t.Errorf("Get(nginx-1) state = %v; expected api with 3 replicas", read.Apps.State)
+	}
+
+	if _, err := store.Get(ctx, "nginx-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(nginx-2) error = %v; expected %v", err, ErrNotFound)
+	}
+
+	if err := store.Put(ctx, &NamespaceState{Namespace: "nginx-2", Apps: shared.Apps{State: []shared.Workload{{Name: "web", OriginalReplicas: 1, Phase: shared.PhaseDownscaled}}}}); err != nil {
+		t.Fatalf("Put(nginx-2) error = %v", err)
+	}
+
+	read.Apps.State[0].Phase = shared.PhaseUpscaled
+	if err := store.Put(ctx, read); err != nil {
+		t.Errorf("Put(nginx-1) after writing another namespace error = %v; expected no conflict", err)
+	}
+
+	stale := &NamespaceState{Namespace: "nginx-1", Apps: read.Apps, ResourceVersion: "stale"}
+	if err := store.Put(ctx, stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("Put(nginx-1) with stale version error = %v; expected %v", err, ErrConflict)
+	}
+
+	states, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(states) != 2 {
+		t.Errorf("List() returned %d namespaces; expected 2", len(states))
+	}
+}
+
+func TestConfigMapStoreMigratesLegacyState(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = fake.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "downscaler-cm", Namespace: "downscaler"},
+			Data: map[string]string{
+				"nginx-1.yaml": "status: not_empty\ngroup: default\nstate:\n- api,3,3\n- worker,1,3\n",
+			},
+		})
+		store = NewConfigMapStore(client, "downscaler-cm", "downscaler")
+	)
+
+	legacy, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if !legacy.Apps.NeedsMigration() {
+		t.Fatalf("Get(nginx-1) schema version = %d; expected a legacy version", legacy.Apps.SchemaVersion)
+	}
+
+	if err := Migrate(ctx, store); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	migrated, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) after migration error = %v", err)
+	}
+	if migrated.Apps.SchemaVersion != shared.StateSchemaVersion {
+		t.Errorf("Get(nginx-1) schema version = %d; expected %d", migrated.Apps.SchemaVersion, shared.StateSchemaVersion)
+	}
+	if len(migrated.Apps.State) != 2 || migrated.Apps.State[1].Name != "worker" || migrated.Apps.State[1].Phase != shared.PhaseDownscaled {
+		t.Errorf("Get(nginx-1) state = %v; expected api and worker downscaled", migrated.Apps.State)
+	}
+}
+
+type staticConfigMapLister struct {
+	cm *corev1.ConfigMap
+}
+
+func (l staticConfigMapLister) Synced() bool                                        { return true }
+func (l staticConfigMapLister) CachesConfigMaps(string) bool                        { return true }
+func (l staticConfigMapLister) ConfigMap(string, string) (*corev1.ConfigMap, error) { return l.cm, nil }
+
+func TestConfigMapStoreReadsFromLister(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		cached = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "downscaler-cm", Namespace: "downscaler"},
+			Data:       map[string]string{"nginx-1.yaml": "schemaVersion: 2\nstatus: not_empty\ngroup: default\nstate:\n- kind: Deployment\n  name: api\n  originalReplicas: 3\n  phase: Downscaled\n"},
+		}
+		client = fake.NewSimpleClientset(cached.DeepCopy())
+		lister = &staticConfigMapLister{cm: cached}
+		store  = NewConfigMapStore(client, "downscaler-cm", "downscaler").AddLister(lister)
+	)
+
+	read, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Get(nginx-1) made %d api calls; expected the read to be served from the lister", len(client.Actions()))
+	}
+
+	read.Apps.State[0].Phase = shared.PhaseUpscaled
+	if err := store.Put(ctx, read); err != nil {
+		t.Fatalf("Put(nginx-1) error = %v", err)
+	}
+
+	lagging, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if lagging.Apps.State[0].Phase != shared.PhaseUpscaled {
+		t.Errorf("Get(nginx-1) from a lagging lister phase = %s; expected our own write %s", lagging.Apps.State[0].Phase, shared.PhaseUpscaled)
+	}
+	if err := store.Put(ctx, lagging); err != nil {
+		t.Errorf("Put(nginx-1) with a version read while the lister lags error = %v; expected nil", err)
+	}
+
+	lister.cm, _ = client.CoreV1().ConfigMaps("downscaler").Get(ctx, "downscaler-cm", metav1.GetOptions{})
+	client.ClearActions()
+	if _, err := store.Get(ctx, "nginx-1"); err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Get(nginx-1) made %d api calls; expected the read to be served from the lister once it caught up", len(client.Actions()))
+	}
+}
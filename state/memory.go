@@ -0,0 +1,69 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+type MemoryStore struct {
+	mu         sync.Mutex
+	namespaces map[string]NamespaceState
+	version    int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{namespaces: make(map[string]NamespaceState)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, namespace string) (*NamespaceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, found := s.namespaces[namespace]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyNamespaceState(stored), nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, state *NamespaceState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, found := s.namespaces[state.Namespace]
+	if state.ResourceVersion != "" && (!found || stored.ResourceVersion != state.ResourceVersion) {
+		return ErrConflict
+	}
+
+	s.version++
+	state.ResourceVersion = strconv.Itoa(s.version)
+	s.namespaces[state.Namespace] = *copyNamespaceState(*state)
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]*NamespaceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]*NamespaceState, 0, len(s.namespaces))
+	for _, stored := range s.namespaces {
+		states = append(states, copyNamespaceState(stored))
+	}
+	return states, nil
+}
+
+func (s *MemoryStore) GetWorkload(ctx context.Context, namespace, name string) (*shared.Workload, string, error) {
+	return getWorkloadFromNamespace(ctx, s, namespace, name)
+}
+
+func (s *MemoryStore) PutWorkload(ctx context.Context, namespace string, workload shared.Workload, resourceVersion string) error {
+	return putWorkloadInNamespace(ctx, s, namespace, workload, resourceVersion)
+}
+
+func copyNamespaceState(state NamespaceState) *NamespaceState {
+	state.Apps.State = append([]shared.Workload(nil), state.Apps.State...)
+	return &state
+}
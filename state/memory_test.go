@@ -0,0 +1,73 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+func TestMemoryStoreOptimisticConcurrency(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewMemoryStore()
+	)
+
+	if _, err := store.Get(ctx, "nginx-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(nginx-1) error = %v; expected %v", err, ErrNotFound)
+	}
+
+	initial := &NamespaceState{Namespace: "nginx-1", Apps: shared.Apps{State: []shared.Workload{{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}}}}
+	if err := store.Put(ctx, initial); err != nil {
+		t.Fatalf("Put(nginx-1) error = %v", err)
+	}
+
+	first, _ := store.Get(ctx, "nginx-1")
+	second, _ := store.Get(ctx, "nginx-1")
+
+	first.Apps.State[0].Phase = shared.PhaseUpscaled
+	if err := store.Put(ctx, first); err != nil {
+		t.Fatalf("Put(nginx-1) with current version error = %v", err)
+	}
+
+	second.Apps.State[0].OriginalReplicas = 5
+	if err := store.Put(ctx, second); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Put(nginx-1) with stale version error = %v; expected %v", err, ErrConflict)
+	}
+}
+
+func TestMemoryStoreWorkloads(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewMemoryStore()
+	)
+
+	if err := store.PutWorkload(ctx, "nginx-1", shared.Workload{Name: "api", OriginalReplicas: 3, Phase: shared.PhaseDownscaled}, ""); err != nil {
+		t.Fatalf("PutWorkload(api) error = %v", err)
+	}
+	if err := store.PutWorkload(ctx, "nginx-1", shared.Workload{Name: "worker", OriginalReplicas: 1, Phase: shared.PhaseDownscaled}, ""); err != nil {
+		t.Fatalf("PutWorkload(worker) error = %v", err)
+	}
+
+	workload, version, err := store.GetWorkload(ctx, "nginx-1", "api")
+	if err != nil {
+		t.Fatalf("GetWorkload(api) error = %v", err)
+	}
+	if workload.OriginalReplicas != 3 {
+		t.Errorf("GetWorkload(api) replicas = %d; expected 3", workload.OriginalReplicas)
+	}
+
+	workload.Phase = shared.PhaseUpscaled
+	if err := store.PutWorkload(ctx, "nginx-1", *workload, version); err != nil {
+		t.Fatalf("PutWorkload(api) with current version error = %v", err)
+	}
+	if err := store.PutWorkload(ctx, "nginx-1", *workload, version); !errors.Is(err, ErrConflict) {
+		t.Fatalf("PutWorkload(api) with stale version error = %v; expected %v", err, ErrConflict)
+	}
+
+	namespaceState, _ := store.Get(ctx, "nginx-1")
+	if len(namespaceState.Apps.State) != 2 {
+		t.Errorf("Get(nginx-1) returned %d workloads; expected 2", len(namespaceState.Apps.State))
+	}
+}
@@ -0,0 +1,241 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+)
+
+type DownscalerLister interface {
+	Synced() bool
+	Downscaler(name string) (*unstructured.Unstructured, error)
+}
+
+type StatusStore struct {
+	client    dynamic.Interface
+	lister    DownscalerLister
+	name      string
+	namespace string
+	gvr       schema.GroupVersionResource
+
+	snapshotMu sync.Mutex
+	snapshot   *unstructured.Unstructured
+}
+
+func NewStatusStore(client dynamic.Interface, policyKey string) *StatusStore {
+	namespace, name, _ := cache.SplitMetaNamespaceKey(policyKey)
+
+	resource := shared.Resource
+	if namespace != "" {
+		resource = shared.NamespacedResource
+	}
+
+	return &StatusStore{
+		client:    client,
+		name:      name,
+		namespace: namespace,
+		gvr: schema.GroupVersionResource{
+			Group:    shared.Group,
+			Version:  shared.Version,
+			Resource: resource,
+		},
+	}
+}
+
+func (s *StatusStore) AddLister(lister DownscalerLister) *StatusStore {
+	s.lister = lister
+	return s
+}
+
+// reads are served from the informer when it watches the policy, which is only the cluster
+// scoped Downscaler, Put still reads the api to check the version before the update. The last
+// object read is kept so the state outlives the Downscaler and its workloads can be upscaled
+// once it is deleted
+func (s *StatusStore) downscaler(ctx context.Context) (*unstructured.Unstructured, error) {
+	var (
+		obj    *unstructured.Unstructured
+		err    error
+		source = "api"
+	)
+	if s.lister != nil && s.lister.Synced() && s.namespace == "" {
+		source = "cache"
+		obj, err = s.lister.Downscaler(s.name)
+	} else {
+		obj, err = s.client.Resource(s.gvr).Namespace(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	}
+
+	if apierrors.IsNotFound(err) {
+		if snapshot := s.lastSnapshot(); snapshot != nil {
+			return snapshot, nil
+		}
+	}
+	if err != nil {
+		slog.Error("crd", "kind", "downscaler", "name", s.name, "verb", "get", "source", source, "err", err)
+		return nil, err
+	}
+
+	s.keepSnapshot(obj)
+	return obj, nil
+}
+
+// a lagging informer must not replace the object returned by our own status update
+func (s *StatusStore) keepSnapshot(obj *unstructured.Unstructured) {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	if s.snapshot != nil && olderResourceVersion(obj.GetResourceVersion(), s.snapshot.GetResourceVersion()) {
+		return
+	}
+	s.snapshot = obj.DeepCopy()
+}
+
+func (s *StatusStore) lastSnapshot() *unstructured.Unstructured {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	if s.snapshot == nil {
+		return nil
+	}
+	return s.snapshot.DeepCopy()
+}
+
+func (s *StatusStore) Get(ctx context.Context, namespace string) (*NamespaceState, error) {
+	obj, err := s.downscaler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaceStateFromStatus(obj, namespace)
+}
+
+func (s *StatusStore) Put(ctx context.Context, state *NamespaceState) error {
+	data, err := json.Marshal(state.Apps)
+	if err != nil {
+		return err
+	}
+
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := s.client.Resource(s.gvr).Namespace(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		deleted := false
+		if apierrors.IsNotFound(err) {
+			if obj = s.lastSnapshot(); obj != nil {
+				deleted, err = true, nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if state.ResourceVersion != "" {
+			stored, err := namespaceStateFromStatus(obj, state.Namespace)
+			if err != nil || stored.ResourceVersion != state.ResourceVersion {
+				return ErrConflict
+			}
+		}
+
+		if err := unstructured.SetNestedField(obj.Object, value, "status", "state", state.Namespace); err != nil {
+			return err
+		}
+
+		if deleted {
+			s.keepSnapshot(obj)
+			state.ResourceVersion = contentVersion(state.Apps)
+			return nil
+		}
+
+		updated, err := s.client.Resource(s.gvr).Namespace(s.namespace).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+		if err != nil {
+			if !apierrors.IsConflict(err) {
+				slog.Error("crd", "kind", "downscaler", "name", s.name, "verb", "update status", "err", err)
+			}
+			return err
+		}
+
+		s.keepSnapshot(updated)
+
+		state.ResourceVersion = contentVersion(state.Apps)
+		return nil
+	})
+}
+
+func (s *StatusStore) List(ctx context.Context) ([]*NamespaceState, error) {
+	obj, err := s.downscaler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, _, err := unstructured.NestedMap(obj.Object, "status", "state")
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*NamespaceState, 0, len(namespaces))
+	for namespace := range namespaces {
+		state, err := namespaceStateFromStatus(obj, namespace)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *StatusStore) GetWorkload(ctx context.Context, namespace, name string) (*shared.Workload, string, error) {
+	return getWorkloadFromNamespace(ctx, s, namespace, name)
+}
+
+func (s *StatusStore) PutWorkload(ctx context.Context, namespace string, workload shared.Workload, resourceVersion string) error {
+	return putWorkloadInNamespace(ctx, s, namespace, workload, resourceVersion)
+}
+
+func olderResourceVersion(version, than string) bool {
+	parsed, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return false
+	}
+	parsedThan, err := strconv.ParseUint(than, 10, 64)
+	if err != nil {
+		return false
+	}
+	return parsed < parsedThan
+}
+
+func namespaceStateFromStatus(obj *unstructured.Unstructured, namespace string) (*NamespaceState, error) {
+	value, found, err := unstructured.NestedMap(obj.Object, "status", "state", namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps shared.Apps
+	if err := json.Unmarshal(data, &apps); err != nil {
+		return nil, err
+	}
+
+	return &NamespaceState{
+		Namespace:       namespace,
+		Apps:            apps,
+		ResourceVersion: contentVersion(apps),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type staticDownscalerLister struct {
+	obj *unstructured.Unstructured
+}
+
+func (l staticDownscalerLister) Synced() bool { return true }
+func (l staticDownscalerLister) Downscaler(string) (*unstructured.Unstructured, error) {
+	return l.obj.DeepCopy(), nil
+}
+
+var downscalerGVR = schema.GroupVersionResource{Group: shared.Group, Version: shared.Version, Resource: shared.Resource}
+
+func downscalerWithState() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": shared.Group + "/" + shared.Version,
+		"kind":       "Downscaler",
+		"metadata":   map[string]interface{}{"name": "downscaler"},
+		"status": map[string]interface{}{"state": map[string]interface{}{
+			"nginx-1": map[string]interface{}{
+				"schemaVersion": int64(shared.StateSchemaVersion),
+				"state":         []interface{}{map[string]interface{}{"kind": shared.DeploymentKind, "name": "api", "originalReplicas": int64(3), "phase": string(shared.PhaseDownscaled)}},
+			},
+		}},
+	}}
+}
+
+func TestStatusStoreReadsFromLister(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		cached = downscalerWithState()
+		client = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{downscalerGVR: "DownscalerList"})
+		store  = NewStatusStore(client, "downscaler").AddLister(staticDownscalerLister{obj: cached})
+	)
+
+	read, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) error = %v", err)
+	}
+	if len(read.Apps.State) != 1 || read.Apps.State[0].Name != "api" || read.Apps.State[0].Phase != shared.PhaseDownscaled {
+		t.Errorf("Get(nginx-1) state = %v; expected api downscaled", read.Apps.State)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Get(nginx-1) made %d api calls; expected the read to be served from the informer", len(client.Actions()))
+	}
+}
+
+func TestStatusStoreOutlivesTheDownscaler(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{downscalerGVR: "DownscalerList"}, downscalerWithState())
+		store  = NewStatusStore(client, "downscaler")
+	)
+
+	if _, err := store.List(ctx); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if err := client.Resource(downscalerGVR).Delete(ctx, "downscaler", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete(downscaler) error = %v", err)
+	}
+
+	states, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List() after the delete error = %v", err)
+	}
+	if len(states) != 1 || states[0].Namespace != "nginx-1" || states[0].Apps.State[0].Phase != shared.PhaseDownscaled {
+		t.Fatalf("List() after the delete = %v; expected nginx-1 still downscaled", states)
+	}
+
+	states[0].Apps.State[0].Phase = shared.PhaseUpscaled
+	if err := store.Put(ctx, states[0]); err != nil {
+		t.Fatalf("Put(nginx-1) after the delete error = %v", err)
+	}
+
+	upscaled, err := store.Get(ctx, "nginx-1")
+	if err != nil {
+		t.Fatalf("Get(nginx-1) after the delete error = %v", err)
+	}
+	if upscaled.Apps.State[0].Phase != shared.PhaseUpscaled {
+		t.Errorf("Get(nginx-1) phase = %s; expected %s", upscaled.Apps.State[0].Phase, shared.PhaseUpscaled)
+	}
+}
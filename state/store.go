@@ -0,0 +1,150 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/adalbertjnr/downscaler/input"
+	"github.com/adalbertjnr/downscaler/shared"
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	ErrNotFound = errors.New("state not found")
+	ErrConflict = errors.New("state was modified since it was read")
+)
+
+type NamespaceState struct {
+	Namespace       string
+	Apps            shared.Apps
+	ResourceVersion string
+}
+
+type StateStore interface {
+	Get(ctx context.Context, namespace string) (*NamespaceState, error)
+	Put(ctx context.Context, state *NamespaceState) error
+	List(ctx context.Context) ([]*NamespaceState, error)
+	GetWorkload(ctx context.Context, namespace, name string) (*shared.Workload, string, error)
+	PutWorkload(ctx context.Context, namespace string, workload shared.Workload, resourceVersion string) error
+}
+
+type Lister interface {
+	ConfigMapLister
+	DeploymentLister
+	DownscalerLister
+}
+
+func New(args *input.FromArgs, client kubernetes.Interface, dynamicClient dynamic.Interface, lister Lister, policyName string) (StateStore, error) {
+	switch args.StateBackend {
+	case shared.StateBackendConfigMap:
+		return NewConfigMapStore(client, ConfigMapNameFor(args.ConfigMapName, policyName), args.ConfigMapNamespace).AddLister(lister), nil
+	case shared.StateBackendAnnotations:
+		return NewAnnotationStore(client).AddLister(lister), nil
+	case shared.StateBackendStatus:
+		return NewStatusStore(dynamicClient, policyName).AddLister(lister), nil
+	case shared.StateBackendMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown state backend %q", args.StateBackend)
+}
+
+func ConfigMapNameFor(configMapName, policyName string) string {
+	if policyName == "" || policyName == shared.DownscalerNamespace {
+		return configMapName
+	}
+	return configMapName + "-" + strings.Replace(policyName, "/", ".", 1)
+}
+
+func Migrate(ctx context.Context, store StateStore) error {
+	states, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, namespaceState := range states {
+		if !namespaceState.Apps.NeedsMigration() {
+			continue
+		}
+
+		from := namespaceState.Apps.SchemaVersion
+		namespaceState.Apps.SchemaVersion = shared.StateSchemaVersion
+		for i := range namespaceState.Apps.State {
+			if namespaceState.Apps.State[i].Kind == "" {
+				namespaceState.Apps.State[i].Kind = shared.DeploymentKind
+			}
+		}
+
+		if err := store.Put(ctx, namespaceState); err != nil {
+			slog.Error("state migration", "namespace", namespaceState.Namespace, "from schema", from, "to schema", shared.StateSchemaVersion, "err", err)
+			return err
+		}
+		slog.Info("state migration", "namespace", namespaceState.Namespace, "from schema", from, "to schema", shared.StateSchemaVersion, "workloads", len(namespaceState.Apps.State), "unparsed", len(namespaceState.Apps.Unparsed), "status", "migrated")
+	}
+	return nil
+}
+
+func contentVersion(apps shared.Apps) string {
+	data, err := yaml.Marshal(apps)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+func emptyNamespaceState(namespace string) *NamespaceState {
+	return &NamespaceState{
+		Namespace: namespace,
+		Apps: shared.Apps{
+			SchemaVersion: shared.StateSchemaVersion,
+			Status:        shared.NotEmptyNamespace,
+			Group:         shared.DefaultGroup,
+		},
+	}
+}
+
+func getWorkloadFromNamespace(ctx context.Context, store StateStore, namespace, name string) (*shared.Workload, string, error) {
+	current, err := store.Get(ctx, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, workload := range current.Apps.State {
+		if workload.Name == name {
+			return &workload, current.ResourceVersion, nil
+		}
+	}
+	return nil, "", ErrNotFound
+}
+
+func putWorkloadInNamespace(ctx context.Context, store StateStore, namespace string, workload shared.Workload, resourceVersion string) error {
+	current, err := store.Get(ctx, namespace)
+	if errors.Is(err, ErrNotFound) {
+		current = emptyNamespaceState(namespace)
+	} else if err != nil {
+		return err
+	}
+
+	if resourceVersion != "" && current.ResourceVersion != resourceVersion {
+		return ErrConflict
+	}
+
+	replaced := false
+	for i, stored := range current.Apps.State {
+		if stored.Name == workload.Name {
+			current.Apps.State[i] = workload
+			replaced = true
+		}
+	}
+	if !replaced {
+		current.Apps.State = append(current.Apps.State, workload)
+	}
+
+	current.Apps.SchemaVersion = shared.StateSchemaVersion
+	return store.Put(ctx, current)
+}
@@ -0,0 +1,419 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type NamespacePhase string
+
+const (
+	PhaseUp            NamespacePhase = "Up"
+	PhaseDown          NamespacePhase = "Down"
+	PhaseTransitioning NamespacePhase = "Transitioning"
+	PhaseError         NamespacePhase = "Error"
+
+	ConditionReady       = "Ready"
+	ConditionDegraded    = "Degraded"
+	ConditionConflicting = "Conflicting"
+
+	ReasonPolicyApplied    = "PolicyApplied"
+	ReasonPolicyInvalid    = "PolicyInvalid"
+	ReasonNamespacesFailed = "NamespacesFailed"
+	ReasonAsExpected       = "AsExpected"
+	ReasonNamespaceClaimed = "NamespaceClaimed"
+	ReasonNoConflicts      = "NoConflicts"
+
+	WorkloadAdopted = "Adopted"
+	WorkloadPruned  = "Pruned"
+
+	flushInterval = time.Second * 10
+
+	maxPlannedActions  = 50
+	maxWorkloadChanges = 10
+)
+
+type Patcher interface {
+	PatchDownscalerStatus(ctx context.Context, name string, patch []byte) error
+}
+
+type RuleStatus struct {
+	Name          string       `json:"name"`
+	Namespaces    []string     `json:"namespaces"`
+	NextDownscale *metav1.Time `json:"nextDownscale,omitempty"`
+	NextUpscale   *metav1.Time `json:"nextUpscale,omitempty"`
+}
+
+type WorkloadChange struct {
+	Time     metav1.Time `json:"time"`
+	Name     string      `json:"name"`
+	Action   string      `json:"action"`
+	Replicas int32       `json:"replicas,omitempty"`
+}
+
+type NamespaceStatus struct {
+	Name               string           `json:"name"`
+	Phase              NamespacePhase   `json:"phase"`
+	Rule               string           `json:"rule,omitempty"`
+	Message            string           `json:"message,omitempty"`
+	LastTransitionTime metav1.Time      `json:"lastTransitionTime"`
+	WorkloadChanges    []WorkloadChange `json:"workloadChanges,omitempty"`
+	UnparsedState      []string         `json:"unparsedState,omitempty"`
+}
+
+type RuleOverlap struct {
+	Namespace  string   `json:"namespace"`
+	Rules      []string `json:"rules"`
+	Winner     string   `json:"winner"`
+	Resolution string   `json:"resolution"`
+}
+
+type PlannedAction struct {
+	Time      metav1.Time `json:"time"`
+	Action    string      `json:"action"`
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	From      int32       `json:"from,omitempty"`
+	To        int32       `json:"to,omitempty"`
+	Rule      string      `json:"rule,omitempty"`
+}
+
+type DownscalerStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration"`
+	DryRun             bool               `json:"dryRun,omitempty"`
+	Rules              []RuleStatus       `json:"rules"`
+	Namespaces         []NamespaceStatus  `json:"namespaces"`
+	PlannedActions     []PlannedAction    `json:"plannedActions,omitempty"`
+	Overlaps           []RuleOverlap      `json:"overlaps,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions"`
+}
+
+type Reporter struct {
+	mu         sync.Mutex
+	client     Patcher
+	name       string
+	generation int64
+	rules      map[string]RuleStatus
+	namespaces map[string]NamespaceStatus
+	conditions []metav1.Condition
+	dryRun     bool
+	planned    []PlannedAction
+	overlaps   []RuleOverlap
+	dirty      bool
+}
+
+func NewReporter(client Patcher, name string) *Reporter {
+	return &Reporter{
+		client:     client,
+		name:       name,
+		rules:      make(map[string]RuleStatus),
+		namespaces: make(map[string]NamespaceStatus),
+	}
+}
+
+func (r *Reporter) PolicyApplied(generation int64, rules map[string][]string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation = generation
+	for name := range r.rules {
+		if _, found := rules[name]; !found {
+			delete(r.rules, name)
+		}
+	}
+	for name, namespaces := range rules {
+		rule := r.rules[name]
+		rule.Name = name
+		rule.Namespaces = namespaces
+		r.rules[name] = rule
+	}
+
+	r.setCondition(ConditionReady, metav1.ConditionTrue, ReasonPolicyApplied, "the policy was validated and the rules were scheduled")
+	r.refreshDegraded()
+	r.dirty = true
+}
+
+func (r *Reporter) PolicyRemoved() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation = 0
+	r.rules = make(map[string]RuleStatus)
+	r.namespaces = make(map[string]NamespaceStatus)
+	r.conditions = nil
+	r.planned = nil
+	r.overlaps = nil
+	r.dirty = false
+}
+
+func (r *Reporter) RuleOverlaps(overlaps []RuleOverlap) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.overlaps = append([]RuleOverlap(nil), overlaps...)
+	r.dirty = true
+}
+
+func (r *Reporter) NamespaceConflicts(conflicts []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(conflicts) > 0 {
+		r.setCondition(ConditionConflicting, metav1.ConditionTrue, ReasonNamespaceClaimed, strings.Join(conflicts, "; "))
+	} else {
+		r.setCondition(ConditionConflicting, metav1.ConditionFalse, ReasonNoConflicts, "no namespace is claimed by another Downscaler")
+	}
+	r.dirty = true
+}
+
+func (r *Reporter) PolicyInvalid(generation int64, errors []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation = generation
+	message := strings.Join(errors, "; ")
+	r.setCondition(ConditionReady, metav1.ConditionFalse, ReasonPolicyInvalid, message)
+	r.setCondition(ConditionDegraded, metav1.ConditionTrue, ReasonPolicyInvalid, message)
+	r.dirty = true
+}
+
+func (r *Reporter) RuleSchedule(name string, namespaces []string, nextDownscale, nextUpscale time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	down, up := metav1.NewTime(nextDownscale), metav1.NewTime(nextUpscale)
+	rule := r.rules[name]
+	if rule.NextDownscale != nil && rule.NextDownscale.Equal(&down) && rule.NextUpscale != nil && rule.NextUpscale.Equal(&up) {
+		return
+	}
+
+	rule.Name = name
+	rule.Namespaces = namespaces
+	rule.NextDownscale = &down
+	rule.NextUpscale = &up
+	r.rules[name] = rule
+	r.dirty = true
+}
+
+func (r *Reporter) NamespacePhase(namespace, rule string, phase NamespacePhase, message string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, found := r.namespaces[namespace]
+	if found && current.Phase == phase && current.Message == message {
+		return
+	}
+
+	transitionTime := current.LastTransitionTime
+	if !found || current.Phase != phase {
+		transitionTime = metav1.Now()
+	}
+
+	r.namespaces[namespace] = NamespaceStatus{
+		Name:               namespace,
+		Phase:              phase,
+		Rule:               rule,
+		Message:            message,
+		LastTransitionTime: transitionTime,
+		WorkloadChanges:    current.WorkloadChanges,
+		UnparsedState:      current.UnparsedState,
+	}
+	r.refreshDegraded()
+	r.dirty = true
+}
+
+func (r *Reporter) WorkloadChanged(namespace, name, action string, replicas int32) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, found := r.namespaces[namespace]
+	if !found {
+		current = NamespaceStatus{Name: namespace, Phase: PhaseDown, LastTransitionTime: metav1.Now()}
+	}
+
+	changes := append(current.WorkloadChanges, WorkloadChange{Time: metav1.Now(), Name: name, Action: action, Replicas: replicas})
+	if len(changes) > maxWorkloadChanges {
+		changes = changes[len(changes)-maxWorkloadChanges:]
+	}
+	current.WorkloadChanges = changes
+	r.namespaces[namespace] = current
+	r.dirty = true
+}
+
+func (r *Reporter) UnparsedState(namespace string, entries []string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, found := r.namespaces[namespace]
+	if !found && len(entries) == 0 {
+		return
+	}
+	if !found {
+		current = NamespaceStatus{Name: namespace, Phase: PhaseDown, LastTransitionTime: metav1.Now()}
+	}
+	if strings.Join(current.UnparsedState, "\n") == strings.Join(entries, "\n") {
+		return
+	}
+
+	current.UnparsedState = entries
+	r.namespaces[namespace] = current
+	r.dirty = true
+}
+
+func (r *Reporter) PlannedAction(action PlannedAction) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dryRun = true
+	r.planned = append(r.planned, action)
+	if len(r.planned) > maxPlannedActions {
+		r.planned = r.planned[len(r.planned)-maxPlannedActions:]
+	}
+	r.dirty = true
+}
+
+func (r *Reporter) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush(ctx)
+		case <-ctx.Done():
+			r.Flush(context.Background())
+			return
+		}
+	}
+}
+
+func (r *Reporter) Flush(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return
+	}
+	snapshot := r.snapshot()
+	r.dirty = false
+	r.mu.Unlock()
+
+	patch, err := json.Marshal(map[string]interface{}{"status": snapshot})
+	if err != nil {
+		slog.Error("status", "kind", "downscaler", "name", r.name, "verb", "marshal", "err", err)
+		return
+	}
+
+	if err := r.client.PatchDownscalerStatus(ctx, r.name, patch); err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+	}
+}
+
+func (r *Reporter) Snapshot() DownscalerStatus {
+	if r == nil {
+		return DownscalerStatus{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot()
+}
+
+func (r *Reporter) snapshot() DownscalerStatus {
+	status := DownscalerStatus{
+		ObservedGeneration: r.generation,
+		DryRun:             r.dryRun,
+		PlannedActions:     append([]PlannedAction(nil), r.planned...),
+		Overlaps:           append([]RuleOverlap(nil), r.overlaps...),
+		Rules:              make([]RuleStatus, 0, len(r.rules)),
+		Namespaces:         make([]NamespaceStatus, 0, len(r.namespaces)),
+		Conditions:         append([]metav1.Condition(nil), r.conditions...),
+	}
+
+	for _, rule := range r.rules {
+		status.Rules = append(status.Rules, rule)
+	}
+	sort.Slice(status.Rules, func(i, j int) bool { return status.Rules[i].Name < status.Rules[j].Name })
+
+	for _, namespace := range r.namespaces {
+		status.Namespaces = append(status.Namespaces, namespace)
+	}
+	sort.Slice(status.Namespaces, func(i, j int) bool { return status.Namespaces[i].Name < status.Namespaces[j].Name })
+
+	return status
+}
+
+func (r *Reporter) refreshDegraded() {
+	if condition := meta.FindStatusCondition(r.conditions, ConditionReady); condition != nil && condition.Reason == ReasonPolicyInvalid {
+		return
+	}
+
+	failed := make([]string, 0)
+	for name, namespace := range r.namespaces {
+		if namespace.Phase == PhaseError {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+
+	if len(failed) > 0 {
+		r.setCondition(ConditionDegraded, metav1.ConditionTrue, ReasonNamespacesFailed, "scaling failed for namespace(s): "+strings.Join(failed, ", "))
+		return
+	}
+	r.setCondition(ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected, "every namespace was scaled as expected")
+}
+
+func (r *Reporter) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&r.conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: r.generation,
+	})
+}
@@ -0,0 +1,199 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adalbertjnr/downscaler/shared"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	ExpressionKey      = "namespace"
+	ExpressionOperator = "exclude"
+
+	ErrEmptyRules                = "at least one rule is required"
+	ErrEmptyNamespaces           = "at least one namespace is required"
+	ErrNotValidRecurrence        = "expected day names such as MON-FRI or MON-WED-FRI"
+	ErrNotValidWithCron          = "expected a time window such as 08:00-20:00 or 08:00-08:00PM"
+	ErrNotValidUpscaleSpread     = "expected a non negative duration such as 5m"
+	ErrNamespaceScheduledByRules = "the namespace is already scheduled by rules[%d], set overlapResolution to resolve it"
+)
+
+var weekdays = map[string]struct{}{
+	"MON": {}, "TUE": {}, "WED": {}, "THU": {}, "FRI": {}, "SAT": {}, "SUN": {},
+}
+
+type paths struct {
+	timeZone   *field.Path
+	recurrence *field.Path
+	expression *field.Path
+	rules      *field.Path
+	overlap    *field.Path
+	rule       func(i int) *field.Path
+	namespace  func(rule, i int) *field.Path
+}
+
+// a NamespaceDownscaler is validated as the Downscaler it is converted to, but its errors
+// point to the fields of its own spec
+func pathsFor(policy *shared.DownscalerPolicy) paths {
+	spec := field.NewPath("spec")
+	if policy.Kind == shared.NamespaceDownscalerKind {
+		return paths{
+			timeZone:   spec.Child("timeZone"),
+			recurrence: spec.Child("recurrence"),
+			expression: spec,
+			rules:      spec,
+			overlap:    spec.Child("overlapResolution"),
+			rule:       func(int) *field.Path { return spec },
+			namespace:  func(int, int) *field.Path { return field.NewPath("metadata", "namespace") },
+		}
+	}
+
+	timeBlock := spec.Child("executionOpts", "time")
+	downscaler := timeBlock.Child("downscaler")
+	timeRules := downscaler.Child("withNamespaceOpts", "downscaleNamespacesWithTimeRules")
+	rules := timeRules.Child("rules")
+	return paths{
+		timeZone:   timeBlock.Child("timeZone"),
+		recurrence: timeBlock.Child("recurrence"),
+		expression: downscaler.Child("downscalerSelectorTerms", "matchExpressions"),
+		rules:      rules,
+		overlap:    timeRules.Child("overlapResolution"),
+		rule:       rules.Index,
+		namespace:  func(rule, i int) *field.Path { return rules.Index(rule).Child("namespaces").Index(i) },
+	}
+}
+
+func NamespacePath(policy *shared.DownscalerPolicy, rule, i int) *field.Path {
+	return pathsFor(policy).namespace(rule, i)
+}
+
+func Policy(policy *shared.DownscalerPolicy) field.ErrorList {
+	var (
+		errs       = field.ErrorList{}
+		path       = pathsFor(policy)
+		timeBlock  = policy.Spec.ExecutionOpts.Time
+		expression = timeBlock.Downscaler.DownscalerSelectorTerms.MatchExpressions
+		rules      = timeBlock.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules
+		resolution = timeBlock.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.OverlapResolution
+	)
+
+	if timeBlock.TimeZone == "" {
+		errs = append(errs, field.Required(path.timeZone, ""))
+	} else if _, err := time.LoadLocation(timeBlock.TimeZone); err != nil {
+		errs = append(errs, field.Invalid(path.timeZone, timeBlock.TimeZone, err.Error()))
+	}
+
+	if timeBlock.Recurrence == "" {
+		errs = append(errs, field.Required(path.recurrence, ""))
+	} else if !validRecurrence(timeBlock.Recurrence) {
+		errs = append(errs, field.Invalid(path.recurrence, timeBlock.Recurrence, ErrNotValidRecurrence))
+	}
+
+	if expression.Key != ExpressionKey {
+		errs = append(errs, field.NotSupported(path.expression.Child("key"), expression.Key, []string{ExpressionKey}))
+	}
+	if expression.Operator != ExpressionOperator {
+		errs = append(errs, field.NotSupported(path.expression.Child("operator"), expression.Operator, []string{ExpressionOperator}))
+	}
+
+	if len(rules) == 0 {
+		errs = append(errs, field.Required(path.rules, ErrEmptyRules))
+	}
+
+	switch resolution {
+	case "", shared.OverlapReject, shared.OverlapFirstRule, shared.OverlapMostRestrictive, shared.OverlapLongestUptime:
+	default:
+		errs = append(errs, field.NotSupported(path.overlap, resolution, []string{
+			shared.OverlapReject, shared.OverlapFirstRule, shared.OverlapMostRestrictive, shared.OverlapLongestUptime,
+		}))
+	}
+	rejectOverlaps := resolution == "" || resolution == shared.OverlapReject
+
+	claims := make(map[string]int)
+	for i, rule := range rules {
+		errs = append(errs, validateRule(path.rule(i), rule)...)
+
+		for j, namespace := range rule.Namespaces {
+			claimedBy, claimed := claims[namespace]
+			if !claimed {
+				claims[namespace] = i
+				continue
+			}
+			if !rejectOverlaps {
+				continue
+			}
+			errs = append(errs, duplicateNamespace(path.namespace(i, j), namespace, claimedBy))
+		}
+	}
+	return errs
+}
+
+func validateRule(path *field.Path, rule shared.Rule) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(rule.Namespaces) == 0 {
+		errs = append(errs, field.Required(path.Child("namespaces"), ErrEmptyNamespaces))
+	}
+
+	if rule.WithCron == "" {
+		errs = append(errs, field.Required(path.Child("withCron"), ""))
+	} else if !validWithCron(rule.WithCron) {
+		errs = append(errs, field.Invalid(path.Child("withCron"), rule.WithCron, ErrNotValidWithCron))
+	}
+
+	if rule.UpscaleSpread != "" {
+		if spread, err := time.ParseDuration(rule.UpscaleSpread); err != nil || spread < 0 {
+			errs = append(errs, field.Invalid(path.Child("upscaleSpread"), rule.UpscaleSpread, ErrNotValidUpscaleSpread))
+		}
+	}
+
+	switch rule.UpscaleSpreadMode {
+	case "", shared.SpreadModeEven, shared.SpreadModeJitter:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("upscaleSpreadMode"), rule.UpscaleSpreadMode, []string{shared.SpreadModeEven, shared.SpreadModeJitter}))
+	}
+	return errs
+}
+
+func duplicateNamespace(path *field.Path, namespace string, claimedBy int) *field.Error {
+	err := field.Duplicate(path, namespace)
+	err.Detail = fmt.Sprintf(ErrNamespaceScheduledByRules, claimedBy)
+	return err
+}
+
+func validRecurrence(recurrence string) bool {
+	for _, day := range strings.Split(recurrence, "-") {
+		if _, found := weekdays[day]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+func validWithCron(withCron string) bool {
+	timeParts := strings.SplitN(withCron, "-", shared.ExpectedTimeParts)
+	if len(timeParts) != shared.ExpectedTimeParts {
+		return false
+	}
+
+	upscaleFormat, downscaleFormat := shared.TimeFormat, shared.TimeFormat
+	if strings.Contains(timeParts[1], "PM") {
+		upscaleFormat, downscaleFormat = shared.TimeFormat12Ups, shared.TimeFormat12Down
+	}
+	if _, err := time.Parse(upscaleFormat, timeParts[0]); err != nil {
+		return false
+	}
+	_, err := time.Parse(downscaleFormat, timeParts[1])
+	return err == nil
+}
+
+func Messages(errs field.ErrorList) []string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return messages
+}
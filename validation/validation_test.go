@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adalbertjnr/downscaler/shared"
+)
+
+func TestPolicy(t *testing.T) {
+	const rules = "spec.executionOpts.time.downscaler.withNamespaceOpts.downscaleNamespacesWithTimeRules.rules"
+
+	downscaler := func(mutate func(policy *shared.DownscalerPolicy)) *shared.DownscalerPolicy {
+		policy := &shared.DownscalerPolicy{Kind: shared.DownscalerKind}
+		policy.Spec.ExecutionOpts.Time.TimeZone = "America/Sao_Paulo"
+		policy.Spec.ExecutionOpts.Time.Recurrence = "MON-FRI"
+		policy.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Key = ExpressionKey
+		policy.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Operator = ExpressionOperator
+		policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = []shared.Rule{
+			{Namespaces: []string{"nginx-1"}, WithCron: "08:00-20:00"},
+			{Namespaces: []string{"nginx-2", "nginx-3"}, WithCron: "07:00-09:00PM", UpscaleSpread: "5m", UpscaleSpreadMode: shared.SpreadModeJitter},
+		}
+		if mutate != nil {
+			mutate(policy)
+		}
+		return policy
+	}
+	rule := func(policy *shared.DownscalerPolicy, i int) *shared.Rule {
+		return &policy.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules[i]
+	}
+
+	namespaceDownscaler := &shared.NamespaceDownscalerPolicy{}
+	namespaceDownscaler.Metadata.Namespace = "nginx-7"
+	namespaceDownscaler.Spec.TimeZone = "America/Sao_Paulo"
+	namespaceDownscaler.Spec.Recurrence = "MON-WED-FRI"
+	namespaceDownscaler.Spec.WithCron = "8h-20h"
+	namespaceDownscaler.Spec.UpscaleSpread = "-5m"
+
+	tests := []struct {
+		name           string
+		policy         *shared.DownscalerPolicy
+		expectedFields []string
+	}{
+		{"Valid", downscaler(nil), nil},
+		{"Time zone not loadable", downscaler(func(p *shared.DownscalerPolicy) { p.Spec.ExecutionOpts.Time.TimeZone = "Mars/Olympus_Mons" }), []string{"spec.executionOpts.time.timeZone"}},
+		{"Recurrence with unknown day", downscaler(func(p *shared.DownscalerPolicy) { p.Spec.ExecutionOpts.Time.Recurrence = "mon-fri" }), []string{"spec.executionOpts.time.recurrence"}},
+		{"Operator not supported", downscaler(func(p *shared.DownscalerPolicy) {
+			p.Spec.ExecutionOpts.Time.Downscaler.DownscalerSelectorTerms.MatchExpressions.Operator = "include"
+		}), []string{"spec.executionOpts.time.downscaler.downscalerSelectorTerms.matchExpressions.operator"}},
+		{"No rules", downscaler(func(p *shared.DownscalerPolicy) {
+			p.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.Rules = nil
+		}), []string{rules}},
+		{"Not valid rule", downscaler(func(p *shared.DownscalerPolicy) {
+			rule(p, 1).Namespaces = nil
+			rule(p, 1).WithCron = "07:00-21:00PM"
+			rule(p, 1).UpscaleSpreadMode = "random"
+		}), []string{rules + "[1].namespaces", rules + "[1].withCron", rules + "[1].upscaleSpreadMode"}},
+		{"Namespace in two rules", downscaler(func(p *shared.DownscalerPolicy) { rule(p, 1).Namespaces = []string{"nginx-2", "nginx-1"} }), []string{rules + "[1].namespaces[1]"}},
+		{"Namespace in two rules with a resolution", downscaler(func(p *shared.DownscalerPolicy) {
+			rule(p, 1).Namespaces = []string{"nginx-2", "nginx-1"}
+			p.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.OverlapResolution = shared.OverlapLongestUptime
+		}), nil},
+		{"Resolution not supported", downscaler(func(p *shared.DownscalerPolicy) {
+			p.Spec.ExecutionOpts.Time.Downscaler.WithNamespaceOpts.DownscaleNamespacesWithTimeRules.OverlapResolution = "lastRule"
+		}), []string{"spec.executionOpts.time.downscaler.withNamespaceOpts.downscaleNamespacesWithTimeRules.overlapResolution"}},
+		{"Namespace downscaler", namespaceDownscaler.DownscalerPolicy(), []string{"spec.withCron", "spec.upscaleSpread"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Policy(tt.policy)
+
+			fields := make([]string, len(errs))
+			for i, err := range errs {
+				fields[i] = err.Field
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.expectedFields, ",") {
+				t.Errorf("Policy() fields = %v; expected %v (%v)", fields, tt.expectedFields, errs)
+			}
+		})
+	}
+}
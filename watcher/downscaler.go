@@ -5,8 +5,9 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/adalbertjnr/downscaler/common"
+	"github.com/adalbertjnr/downscaler/health"
 	"github.com/adalbertjnr/downscaler/kas"
+	"github.com/adalbertjnr/downscaler/metrics"
 	"github.com/adalbertjnr/downscaler/shared"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,14 +16,22 @@ import (
 
 type Watcher struct {
 	RtObjectch chan runtime.Object
+	Deletedch  chan runtime.Object
+	health     *health.Checker
 }
 
 func New() *Watcher {
 	return &Watcher{
 		RtObjectch: make(chan runtime.Object),
+		Deletedch:  make(chan runtime.Object),
 	}
 }
 
+func (w *Watcher) AddHealthChecker(checker *health.Checker) *Watcher {
+	w.health = checker
+	return w
+}
+
 func (w *Watcher) DownscalerKind(
 	ctx context.Context,
 	metadata shared.Metadata,
@@ -32,35 +41,54 @@ func (w *Watcher) DownscalerKind(
 	for {
 		watcher, err := client.GetWatcherByDownscalerCRD(
 			ctx,
+			metadata.Resource,
 			metadata.Name,
 			metadata.Namespace,
 		)
 		if err != nil {
-			slog.Error("error initializing a new configmap watcher", "next retry", "10 seconds", "error", err)
+			slog.Error("error initializing a new downscaler watcher", "resource", metadata.Resource, "next retry", "10 seconds", "error", err)
+			metrics.WatcherRestart()
+			w.health.WatchLost(metadata.Resource)
 			time.Sleep(time.Second * 10)
 			continue
 		}
 
+		w.health.WatchEstablished(metadata.Resource)
+
 	createNewWatcher:
 		for {
 			event, open := <-watcher.ResultChan()
 			if !open {
 				watcher.Stop()
 				slog.Warn("watcher", "status", "closed", "reason", "recycling due to timeout seconds")
+				metrics.WatcherRestart()
+				w.health.WatchLost(metadata.Resource)
 				break createNewWatcher
 			}
 			switch event.Type {
-			case watch.Modified:
-				downscalerData := event.Object.(*unstructured.Unstructured)
-				data := &shared.DownscalerPolicy{}
-				if err := common.UnmarshalDataPolicy(downscalerData, data); err != nil {
-					slog.Error("unmarshaling", "error unmarshaling in the watcher", err)
-				}
-				slog.Info("watcher", "kind", "downscaler", "name", data.Metadata.Name, "status", "updated")
+			case watch.Added, watch.Modified:
+				slog.Info("watcher", "kind", "downscaler", "name", objectName(event.Object), "status", statusByEventType[event.Type])
 				w.RtObjectch <- event.Object
+			case watch.Deleted:
+				slog.Warn("watcher", "kind", "downscaler", "name", objectName(event.Object), "status", statusByEventType[event.Type])
+				w.Deletedch <- event.Object
 			case watch.Error:
 				slog.Error("error updating the object", "resource type", "Downscaler")
 			}
 		}
 	}
 }
+
+var statusByEventType = map[watch.EventType]string{
+	watch.Added:    "added",
+	watch.Modified: "updated",
+	watch.Deleted:  "deleted",
+}
+
+func objectName(object runtime.Object) string {
+	downscalerData, converted := object.(*unstructured.Unstructured)
+	if !converted {
+		return ""
+	}
+	return downscalerData.GetName()
+}
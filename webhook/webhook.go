@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/adalbertjnr/downscaler/common"
+	"github.com/adalbertjnr/downscaler/shared"
+	"github.com/adalbertjnr/downscaler/validation"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const maxRequestBytes = 1 << 20
+
+type Handler struct{}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		slog.Error("webhook", "verb", "decode admission review", "err", err)
+		http.Error(w, "not valid admission review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		slog.Error("webhook", "verb", "encode admission review", "err", err)
+	}
+}
+
+func (h *Handler) review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation == admissionv1.Delete {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(request.Object.Raw); err != nil {
+		return denied(apierrors.NewBadRequest(err.Error()))
+	}
+
+	policy := &shared.DownscalerPolicy{}
+	if err := common.UnmarshalDataPolicy(object, policy); err != nil {
+		return denied(apierrors.NewBadRequest(err.Error()))
+	}
+
+	if errs := validation.Policy(policy); len(errs) > 0 {
+		slog.Warn("webhook", "kind", object.GetKind(), "name", object.GetName(), "namespace", object.GetNamespace(), "status", "rejected", "errors", validation.Messages(errs))
+		return denied(apierrors.NewInvalid(schema.GroupKind{Group: shared.Group, Kind: object.GetKind()}, object.GetName(), errs))
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(err *apierrors.StatusError) *admissionv1.AdmissionResponse {
+	status := err.Status()
+	return &admissionv1.AdmissionResponse{Allowed: false, Result: &status}
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const validDownscaler = `{
+	"apiVersion": "scheduler.go/v1",
+	"kind": "Downscaler",
+	"metadata": {"name": "downscaler"},
+	"spec": {"executionOpts": {"time": {
+		"timeZone": "America/Sao_Paulo",
+		"recurrence": "MON-FRI",
+		"downscaler": {
+			"downscalerSelectorTerms": {"matchExpressions": {"key": "namespace", "operator": "exclude", "values": ["kube-system"]}},
+			"withNamespaceOpts": {"downscaleNamespacesWithTimeRules": {"rules": [
+				{"namespaces": ["nginx-1"], "withCron": "08:00-20:00"},
+				{"namespaces": ["nginx-2"], "withCron": "07:00-09:00PM"}
+			]}}
+		}
+	}}}
+}`
+
+const invalidDownscaler = `{
+	"apiVersion": "scheduler.go/v1",
+	"kind": "Downscaler",
+	"metadata": {"name": "downscaler"},
+	"spec": {"executionOpts": {"time": {
+		"timeZone": "Mars/Olympus_Mons",
+		"recurrence": "MON-FUN",
+		"downscaler": {
+			"downscalerSelectorTerms": {"matchExpressions": {"key": "namespace", "operator": "include"}},
+			"withNamespaceOpts": {"downscaleNamespacesWithTimeRules": {"rules": [
+				{"namespaces": ["nginx-1"], "withCron": "08:00-25:00"},
+				{"namespaces": ["nginx-1"], "withCron": "08:00-20:00"}
+			]}}
+		}
+	}}}
+}`
+
+const invalidNamespaceDownscaler = `{
+	"apiVersion": "scheduler.go/v1",
+	"kind": "NamespaceDownscaler",
+	"metadata": {"name": "nightly", "namespace": "nginx-7"},
+	"spec": {"timeZone": "America/Sao_Paulo", "recurrence": "MON-FRI", "withCron": "8h-20h"}
+}`
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		operation       admissionv1.Operation
+		object          string
+		expectedAllowed bool
+		expectedCauses  []string
+	}{
+		{"Valid downscaler", admissionv1.Create, validDownscaler, true, nil},
+		{"Every error is returned", admissionv1.Update, invalidDownscaler, false, []string{
+			"spec.executionOpts.time.timeZone",
+			"spec.executionOpts.time.recurrence",
+			"spec.executionOpts.time.downscaler.downscalerSelectorTerms.matchExpressions.operator",
+			"spec.executionOpts.time.downscaler.withNamespaceOpts.downscaleNamespacesWithTimeRules.rules[0].withCron",
+			"spec.executionOpts.time.downscaler.withNamespaceOpts.downscaleNamespacesWithTimeRules.rules[1].namespaces[0]",
+		}},
+		{"Namespace downscaler", admissionv1.Create, invalidNamespaceDownscaler, false, []string{"spec.withCron"}},
+		{"Delete is always allowed", admissionv1.Delete, "{}", true, nil},
+	}
+
+	handler := NewHandler()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{UID: "review-1", Operation: tt.operation, Object: runtime.RawExtension{Raw: []byte(tt.object)}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+
+			review := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &review); err != nil || review.Response == nil {
+				t.Fatalf("not valid response %q: %v", recorder.Body.String(), err)
+			}
+			if review.Response.UID != "review-1" || review.Response.Allowed != tt.expectedAllowed {
+				t.Fatalf("response = (%s, %t); expected (review-1, %t)", review.Response.UID, review.Response.Allowed, tt.expectedAllowed)
+			}
+			if tt.expectedAllowed {
+				return
+			}
+
+			causes := make([]string, 0)
+			for _, cause := range review.Response.Result.Details.Causes {
+				causes = append(causes, cause.Field)
+			}
+			if strings.Join(causes, ",") != strings.Join(tt.expectedCauses, ",") {
+				t.Errorf("causes = %v; expected %v", causes, tt.expectedCauses)
+			}
+		})
+	}
+}